@@ -1,32 +1,104 @@
 package main
 
 import (
+	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"net"
-	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	rpc "buf.build/gen/go/k8sgpt-ai/k8sgpt/grpc/go/schema/v1/schemav1grpc"
 	"github.com/ranakan19/custom-analyzer/pkg/analyzer"
+	"github.com/ranakan19/custom-analyzer/pkg/config"
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/metrics"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	"github.com/ranakan19/custom-analyzer/pkg/server"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
 func main() {
-	fmt.Println("Starting ApplicationSet Analyzer!")
-	var err error
-	address := fmt.Sprintf(":%s", "8085")
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.ListRules {
+		if err := rules.WriteCatalog(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to list rules: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	logger.Info("Starting ApplicationSet Analyzer")
+	address := cfg.ListenAddress
 	lis, err := net.Listen("tcp", address)
 	if err != nil {
-		panic(err)
+		logger.Error("Failed to listen", "address", address, "error", err)
+		os.Exit(1)
+	}
+	var serverOptions []grpc.ServerOption
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := server.NewTLSConfig(cfg.TLS)
+		if err != nil {
+			logger.Error("Failed to configure TLS", "error", err)
+			os.Exit(1)
+		}
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsConfig)))
 	}
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(serverOptions...)
 	reflection.Register(grpcServer)
-	aa := analyzer.NewAnalyzer()
+	aa := analyzer.NewAnalyzer().WithConfig(cfg).WithLogger(logger)
 	rpc.RegisterCustomAnalyzerServiceServer(grpcServer, aa.Handler)
-	fmt.Printf("ApplicationSet Analyzer server listening on %s\n", address)
-	if err := grpcServer.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		fmt.Printf("Server error: %v\n", err)
-		return
+
+	// The overall status ("") serves liveness; the analyzer service status
+	// follows Kubernetes API reachability and serves readiness
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(rpc.CustomAnalyzerService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	if cfg.MetricsAddress != "" {
+		go func() {
+			logger.Info("Metrics server listening", "address", cfg.MetricsAddress)
+			if err := metrics.Serve(ctx, cfg.MetricsAddress); err != nil {
+				logger.Error("Metrics server error", "error", err)
+			}
+		}()
+	}
+	if cfg.Watch {
+		if err := aa.Handler.StartCache(ctx); err != nil {
+			logger.Error("Failed to start informer cache", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Watch mode enabled, serving analyses from the informer cache once synced")
+	}
+	go server.WatchHealth(ctx, healthServer, rpc.CustomAnalyzerService_ServiceDesc.ServiceName, aa.Handler, cfg.HealthCheckInterval.Duration)
+
+	logger.Info("ApplicationSet Analyzer server listening", "address", address, "tls", cfg.TLS.Enabled())
+	if err := server.Serve(ctx, grpcServer, lis, healthServer, cfg.ShutdownTimeout.Duration); err != nil {
+		logger.Error("Server error", "error", err)
+		os.Exit(1)
 	}
-} 
\ No newline at end of file
+	logger.Info("ApplicationSet Analyzer stopped")
+}
@@ -0,0 +1,169 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// checkPermissions is the pseudo check under which missing permissions are recorded in the findings metric
+const checkPermissions = "permissions"
+
+var selfSubjectAccessReviewGVR = authorizationv1.SchemeGroupVersion.WithResource("selfsubjectaccessreviews")
+
+// deniedListAccess reviews the verbs the analyzer needs to list gvr in each configured
+// namespace, or cluster-wide if none are configured, and returns a description of each
+// denied permission
+func (a *Handler) deniedListAccess(ctx context.Context, gvr schema.GroupVersionResource) ([]string, error) {
+	verbs := []string{"list"}
+	if a.cache != nil {
+		verbs = append(verbs, "watch")
+	}
+	return a.deniedAccess(ctx, gvr, verbs, a.scopedNamespaces())
+}
+
+// deniedGeneratorAccess reviews the permissions the generator checks need to get the
+// secrets referenced by generators, which live in the ApplicationSet's namespace or the
+// Argo CD namespace, and the ConfigMaps and plugin Services in the Argo CD namespace
+func (a *Handler) deniedGeneratorAccess(ctx context.Context) ([]string, error) {
+	secretNamespaces := a.scopedNamespaces()
+	if secretNamespaces[0] != metav1.NamespaceAll && !contains(secretNamespaces, a.config.ArgoCDNamespace) {
+		secretNamespaces = append(secretNamespaces, a.config.ArgoCDNamespace)
+	}
+	argocdNamespace := []string{a.config.ArgoCDNamespace}
+
+	var denied []string
+	for _, review := range []struct {
+		gvr        schema.GroupVersionResource
+		namespaces []string
+	}{
+		{secretGVR, secretNamespaces},
+		{configMapGVR, argocdNamespace},
+		{serviceGVR, argocdNamespace},
+	} {
+		d, err := a.deniedAccess(ctx, review.gvr, []string{"get"}, review.namespaces)
+		if err != nil {
+			return denied, err
+		}
+		denied = append(denied, d...)
+	}
+	return denied, nil
+}
+
+// scopedNamespaces returns the configured namespaces, or metav1.NamespaceAll if none are configured
+func (a *Handler) scopedNamespaces() []string {
+	if len(a.config.Namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return append([]string{}, a.config.Namespaces...)
+}
+
+// accessReview is a cached result of the permission preflight
+type accessReview struct {
+	denied   []string
+	reviewed time.Time
+}
+
+// deniedAccess issues a SelfSubjectAccessReview for each of verbs on gvr in each of
+// namespaces and returns a description of each denied permission. Without an
+// authorization client the permissions are assumed to be granted. In watch mode the
+// result is cached and reviewed again once per resync period.
+func (a *Handler) deniedAccess(ctx context.Context, gvr schema.GroupVersionResource, verbs, namespaces []string) ([]string, error) {
+	if a.authorizationClient == nil {
+		return nil, nil
+	}
+	if a.cache == nil {
+		return a.reviewAccess(ctx, gvr, verbs, namespaces)
+	}
+
+	key := fmt.Sprintf("%s %s %s", gvr.String(), strings.Join(verbs, ","), strings.Join(namespaces, ","))
+	resync := a.config.ResyncPeriod.Duration
+	a.cache.reviewsMu.Lock()
+	review, found := a.cache.reviews[key]
+	a.cache.reviewsMu.Unlock()
+	if found && (resync == 0 || time.Since(review.reviewed) < resync) {
+		return review.denied, nil
+	}
+
+	denied, err := a.reviewAccess(ctx, gvr, verbs, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	a.cache.reviewsMu.Lock()
+	a.cache.reviews[key] = accessReview{denied: denied, reviewed: time.Now()}
+	a.cache.reviewsMu.Unlock()
+	return denied, nil
+}
+
+// reviewAccess issues the SelfSubjectAccessReviews of deniedAccess
+func (a *Handler) reviewAccess(ctx context.Context, gvr schema.GroupVersionResource, verbs, namespaces []string) ([]string, error) {
+	var denied []string
+	for _, namespace := range namespaces {
+		for _, verb := range verbs {
+			review := &authorizationv1.SelfSubjectAccessReview{
+				Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+					ResourceAttributes: &authorizationv1.ResourceAttributes{
+						Namespace: namespace,
+						Verb:      verb,
+						Group:     gvr.Group,
+						Version:   gvr.Version,
+						Resource:  gvr.Resource,
+					},
+				},
+			}
+			var result *authorizationv1.SelfSubjectAccessReview
+			err := a.callWithRetry(ctx, selfSubjectAccessReviewGVR, "create", func() error {
+				var err error
+				result, err = a.authorizationClient.SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to review access to %s: %v", gvr.GroupResource(), err)
+			}
+			if !result.Status.Allowed {
+				denied = append(denied, fmt.Sprintf("cannot %s %s %s", verb, gvr.GroupResource(), namespaceScope(namespace)))
+			}
+		}
+	}
+	return denied, nil
+}
+
+// namespaceScope describes a namespace, or all namespaces for metav1.NamespaceAll
+func namespaceScope(namespace string) string {
+	if namespace == metav1.NamespaceAll {
+		return "in all namespaces"
+	}
+	return "in namespace " + namespace
+}
+
+// referenceCheck reports the error of reading reference, which a check of a generator needs,
+// so that the skipped check is not mistaken for a clean result: a MissingPermission finding
+// if the analyzer was not allowed to perform action, or a GeneratorReferenceUnverified
+// finding quoting err for any other failure, such as a server error or an exhausted budget.
+func referenceCheck(appSet *unstructured.Unstructured, kind, location, reference, action string, err error) []*v1.ErrorDetail {
+	if apierrors.IsForbidden(err) {
+		return []*v1.ErrorDetail{finding(rules.MissingPermission, "Missing permission: analyzer cannot %s; the check of ApplicationSet %s/%s %s generator at %s was skipped",
+			action, appSet.GetNamespace(), appSet.GetName(), kind, location)}
+	}
+	return []*v1.ErrorDetail{finding(rules.GeneratorReferenceUnverified, "Could not verify %s referenced by ApplicationSet %s/%s %s generator at %s: %v; the check was skipped",
+		reference, appSet.GetNamespace(), appSet.GetName(), kind, location, err)}
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,29 +2,61 @@ package analyzer
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	rpc "buf.build/gen/go/k8sgpt-ai/k8sgpt/grpc/go/schema/v1/schemav1grpc"
 	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/config"
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/metrics"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
+	authorizationv1client "k8s.io/client-go/kubernetes/typed/authorization/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 type Handler struct {
 	rpc.CustomAnalyzerServiceServer
-	dynamicClient dynamic.Interface
+	dynamicClient   dynamic.Interface
+	discoveryClient discovery.DiscoveryInterface
+	// authorizationClient issues the SelfSubjectAccessReviews of the permission preflight
+	authorizationClient authorizationv1client.AuthorizationV1Interface
+	config              *config.Config
+	logger              *slog.Logger
+	// cache serves ApplicationSets and Applications in watch mode; nil otherwise
+	cache *informerCache
+	// resources caches the discovered versions of the Argo CD resources
+	resources *servedResources
+	// clusters holds a handler per kubeconfig context when several clusters are analyzed
+	clusters []cluster
+	// clusterClients holds injected dynamic clients by kubeconfig context for testing
+	clusterClients map[string]dynamic.Interface
+
+	// clientMu guards lazy client initialization and resource discovery, which
+	// may be triggered concurrently by Run and by health checks
+	clientMu sync.Mutex
 }
 
 type Analyzer struct {
 	Handler *Handler
 }
 
-// GVRs for ApplicationSet and Application resources
+// discoveryTimeout bounds each discovery request, including the version request of health checks
+const discoveryTimeout = 30 * time.Second
+
+// Default GVRs for ApplicationSet and Application resources, used when the
+// served versions cannot be discovered
 var (
 	applicationSetGVR = schema.GroupVersionResource{
 		Group:    "argoproj.io",
@@ -38,131 +70,534 @@ var (
 	}
 )
 
-// NewAnalyzer creates a new ApplicationSet analyzer
+// NewAnalyzer creates a new ApplicationSet analyzer with the default configuration
 func NewAnalyzer() *Analyzer {
-	handler := &Handler{}
+	handler := &Handler{
+		config: config.Default(),
+		logger: slog.Default(),
+	}
 	return &Analyzer{
 		Handler: handler,
 	}
 }
 
+// WithConfig sets the configuration used by the analyzer
+func (a *Analyzer) WithConfig(cfg *config.Config) *Analyzer {
+	a.Handler.config = cfg
+	return a
+}
+
 // WithDynamicClient sets the dynamic client for testing
 func (a *Analyzer) WithDynamicClient(client dynamic.Interface) *Analyzer {
 	a.Handler.dynamicClient = client
 	return a
 }
 
+// WithLogger sets the logger used by the analyzer
+func (a *Analyzer) WithLogger(logger *slog.Logger) *Analyzer {
+	a.Handler.logger = logger
+	return a
+}
+
+// WithDiscoveryClient sets the discovery client for testing
+func (a *Analyzer) WithDiscoveryClient(client discovery.DiscoveryInterface) *Analyzer {
+	a.Handler.discoveryClient = client
+	return a
+}
+
+// WithAuthorizationClient sets the authorization client for testing
+func (a *Analyzer) WithAuthorizationClient(client authorizationv1client.AuthorizationV1Interface) *Analyzer {
+	a.Handler.authorizationClient = client
+	return a
+}
+
 // initializeClient initializes the Kubernetes client
 func (a *Handler) initializeClient() error {
+	a.clientMu.Lock()
+	defer a.clientMu.Unlock()
+
 	if a.dynamicClient != nil {
 		return nil
 	}
 
-	config, err := rest.InClusterConfig()
+	restConfig, err := a.restConfig()
 	if err != nil {
-		config, err = clientcmd.BuildConfigFromFlags("", clientcmd.RecommendedHomeFile)
-		if err != nil {
-			return fmt.Errorf("failed to get kubeconfig: %v", err)
-		}
+		return fmt.Errorf("failed to get kubeconfig: %v", err)
 	}
+	restConfig.QPS = a.config.API.QPS
+	restConfig.Burst = a.config.API.Burst
 
-	dynamicClient, err := dynamic.NewForConfig(config)
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
 	if err != nil {
 		return fmt.Errorf("failed to create dynamic client: %v", err)
 	}
 
+	// Discovery calls ignore contexts, so bound them to keep health checks from piling up on a hung API server
+	discoveryConfig := rest.CopyConfig(restConfig)
+	discoveryConfig.Timeout = discoveryTimeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(discoveryConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create discovery client: %v", err)
+	}
+
+	authorizationClient, err := authorizationv1client.NewForConfig(restConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create authorization client: %v", err)
+	}
+
 	a.dynamicClient = dynamicClient
+	a.discoveryClient = discoveryClient
+	a.authorizationClient = authorizationClient
+	return nil
+}
+
+// Ping checks that the Kubernetes API server is reachable. When several clusters are
+// analyzed it is enough that one of them is: a run still reports the findings of the
+// reachable clusters, and reports each unreachable one as a finding.
+func (a *Handler) Ping(ctx context.Context) error {
+	if len(a.config.KubeContexts) > 0 {
+		clusters := a.clusterHandlers()
+		errs := make([]error, len(clusters))
+		var wg sync.WaitGroup
+		for i, c := range clusters {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = c.handler.Ping(ctx)
+			}()
+		}
+		wg.Wait()
+
+		var failed []string
+		for i, c := range clusters {
+			if errs[i] == nil {
+				continue
+			}
+			a.logger.Warn("Cluster is not healthy", "cluster", c.name, "error", errs[i])
+			failed = append(failed, fmt.Sprintf("cluster %s: %v", c.name, errs[i]))
+		}
+		if len(failed) == len(clusters) {
+			return fmt.Errorf("no cluster is healthy: %s", strings.Join(failed, "; "))
+		}
+		return nil
+	}
+
+	if err := a.initializeClient(); err != nil {
+		return err
+	}
+	if a.cache != nil && !a.CacheSynced() {
+		return fmt.Errorf("informer cache has not synced yet")
+	}
+	if a.discoveryClient == nil {
+		// Only a dynamic client was injected, so there is no API server to check
+		return nil
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := a.discoveryClient.ServerVersion()
+		errCh <- err
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			return fmt.Errorf("failed to reach Kubernetes API server: %v", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to reach Kubernetes API server: %v", ctx.Err())
+	}
+}
+
+// restConfig builds the Kubernetes client configuration. Without an explicit
+// kubeconfig or context the in-cluster config is preferred.
+func (a *Handler) restConfig() (*rest.Config, error) {
+	if a.config.Kubeconfig == "" && a.config.KubeContext == "" {
+		if restConfig, err := rest.InClusterConfig(); err == nil {
+			return restConfig, nil
+		}
+	}
+
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = a.config.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: a.config.KubeContext}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides).ClientConfig()
+}
+
+// forEachApplicationSetPage lists ApplicationSets of the given GVR matching the configured
+// selectors in the configured namespaces, or in all namespaces if none are configured, calling
+// fn for each page of results as it arrives. In watch mode the synced cache is passed as a single page.
+func (a *Handler) forEachApplicationSetPage(ctx context.Context, gvr schema.GroupVersionResource, fn func([]unstructured.Unstructured) error) error {
+	if a.CacheSynced() {
+		appSets, err := a.cache.cachedApplicationSets()
+		if err != nil {
+			return err
+		}
+		return fn(appSets)
+	}
+
+	opts := metav1.ListOptions{
+		LabelSelector: a.config.LabelSelector,
+		FieldSelector: a.config.FieldSelector,
+	}
+	if len(a.config.Namespaces) == 0 {
+		return a.listPages(ctx, gvr, metav1.NamespaceAll, opts, fn)
+	}
+
+	for _, namespace := range a.config.Namespaces {
+		if err := a.listPages(ctx, gvr, namespace, opts, fn); err != nil {
+			return fmt.Errorf("namespace %s: %v", namespace, err)
+		}
+	}
 	return nil
 }
 
+// listPages lists resources using the configured page size, calling fn for each page
+// as it arrives so that the full result never has to be held in memory
+func (a *Handler) listPages(ctx context.Context, gvr schema.GroupVersionResource, namespace string, opts metav1.ListOptions, fn func([]unstructured.Unstructured) error) error {
+	opts.Limit = a.config.PageSize
+	for {
+		list, err := a.list(ctx, gvr, namespace, opts)
+		if err != nil {
+			return err
+		}
+		if err := fn(list.Items); err != nil {
+			return err
+		}
+		if list.GetContinue() == "" {
+			return nil
+		}
+		opts.Continue = list.GetContinue()
+	}
+}
+
+// list lists resources of the given GVR in a namespace, or in all namespaces
+// for metav1.NamespaceAll, retrying transient errors and recording every attempt
+// in the API metrics
+func (a *Handler) list(ctx context.Context, gvr schema.GroupVersionResource, namespace string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	var list *unstructured.UnstructuredList
+	err := a.callWithRetry(ctx, gvr, "list", func() error {
+		var err error
+		if namespace == metav1.NamespaceAll {
+			list, err = a.dynamicClient.Resource(gvr).List(ctx, opts)
+		} else {
+			list, err = a.dynamicClient.Resource(gvr).Namespace(namespace).List(ctx, opts)
+		}
+		return err
+	})
+	return list, err
+}
+
+// scopeMessage describes the namespaces and selectors being analyzed
+func (a *Handler) scopeMessage() string {
+	scope := "in the cluster"
+	if len(a.config.Namespaces) > 0 {
+		scope = fmt.Sprintf("in namespace(s) %s", strings.Join(a.config.Namespaces, ", "))
+	}
+
+	var filters []string
+	if a.config.LabelSelector != "" {
+		filters = append(filters, fmt.Sprintf("labels %q", a.config.LabelSelector))
+	}
+	if a.config.FieldSelector != "" {
+		filters = append(filters, fmt.Sprintf("fields %q", a.config.FieldSelector))
+	}
+	if len(filters) > 0 {
+		scope += " matching " + strings.Join(filters, " and ")
+	}
+
+	var appFilters []string
+	if a.config.ApplicationLabelSelector != "" {
+		appFilters = append(appFilters, fmt.Sprintf("labels %q", a.config.ApplicationLabelSelector))
+	}
+	if a.config.ApplicationFieldSelector != "" {
+		appFilters = append(appFilters, fmt.Sprintf("fields %q", a.config.ApplicationFieldSelector))
+	}
+	if len(appFilters) > 0 {
+		scope += fmt.Sprintf(" (Applications matching %s)", strings.Join(appFilters, " and "))
+	}
+	return scope
+}
+
+// finding returns an ErrorDetail reporting a finding of rule
+func finding(rule rules.Rule, format string, args ...interface{}) *v1.ErrorDetail {
+	return &v1.ErrorDetail{Text: rule.Format(format, args...)}
+}
+
+// hasRule reports whether any of findings was reported by rule
+func hasRule(findings []*v1.ErrorDetail, rule rules.Rule) bool {
+	for _, f := range findings {
+		if id, _, _, ok := rules.Parse(f.Text); ok && id == rule.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// appSetResult holds the findings and details for a single ApplicationSet
+type appSetResult struct {
+	namespace string
+	name      string
+	errors    []*v1.ErrorDetail
+	details   []string
+	// suppressed counts the findings dropped by ignore annotations and config suppressions
+	suppressed int
+}
+
+// analyzeApplicationSets analyzes a page of ApplicationSets with a bounded pool of
+// workers and returns the results in page order
+func (a *Handler) analyzeApplicationSets(ctx context.Context, page []unstructured.Unstructured, apps *applicationIndex, stats *runStats) []appSetResult {
+	results := make([]appSetResult, len(page))
+	jobs := make(chan int)
+
+	workers := a.config.Parallelism
+	if workers > len(page) {
+		workers = len(page)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = a.analyzeApplicationSetResult(ctx, &page[i], apps, stats)
+			}
+		}()
+	}
+	for i := range page {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// analyzeApplicationSetResult analyzes a single ApplicationSet within the configured
+// per-ApplicationSet deadline and collects its findings and status details
+func (a *Handler) analyzeApplicationSetResult(ctx context.Context, appSet *unstructured.Unstructured, apps *applicationIndex, stats *runStats) appSetResult {
+	appSetCtx := ctx
+	if timeout := a.config.ApplicationSetTimeout.Duration; timeout > 0 {
+		var cancel context.CancelFunc
+		appSetCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	result := appSetResult{
+		namespace: appSet.GetNamespace(),
+		name:      appSet.GetName(),
+	}
+	result.errors, result.suppressed = a.analyzeApplicationSet(appSetCtx, appSet, apps, stats)
+
+	// An expired Run deadline is not this ApplicationSet's fault and is reported once for the run
+	if errors.Is(appSetCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		result.errors = append(result.errors, finding(rules.AnalysisTimedOut, "Analysis of ApplicationSet %s/%s timed out after %s; results may be incomplete",
+			appSet.GetNamespace(), appSet.GetName(), a.config.ApplicationSetTimeout.Duration))
+	}
+
+	markSensitive(result.errors, sensitiveValues(appSet, apps))
+
+	// Get and display status information
+	status := a.getApplicationSetStatus(appSet)
+	for _, statusDetail := range status {
+		result.details = append(result.details, fmt.Sprintf("  %s", statusDetail))
+	}
+
+	return result
+}
+
 // Run implements the analyzer logic for ApplicationSets
 func (a *Handler) Run(ctx context.Context, req *v1.RunRequest) (*v1.RunResponse, error) {
-	// Add debug logging to help troubleshoot
-	fmt.Printf("ApplicationSet Analyzer: Starting analysis\n")
-	fmt.Printf("ApplicationSet Analyzer: Analyzing all namespaces\n")
+	start := time.Now()
+	logger := a.logger.With("requestID", logging.RequestID(ctx))
+	ctx = logging.NewContext(ctx, logger)
+	ctx = withCallBudget(ctx, a.config.API.CallBudget)
 
-	if err := a.initializeClient(); err != nil {
-		fmt.Printf("ApplicationSet Analyzer: Failed to initialize client: %v\n", err)
-		return &v1.RunResponse{
-			Result: &v1.Result{
-				Name:    "applicationset-analyzer",
-				Details: "Failed to initialize Kubernetes client",
-				Error: []*v1.ErrorDetail{
-					{
-						Text: fmt.Sprintf("Could not connect to Kubernetes cluster: %v", err),
-					},
-				},
-			},
-		}, nil // Return nil error here - the error details are in the response
+	var stats *runStats
+	var result *v1.Result
+	var ok bool
+	if len(a.config.KubeContexts) > 0 {
+		stats = newClusterSetStats()
+		result, ok = a.analyzeClusters(ctx, stats)
+	} else {
+		stats = newRunStats(a.config.KubeContext)
+		result, ok = a.analyze(ctx, stats)
+	}
+	// Also mask the namespaces of permission findings and endpoints quoted in API errors
+	markSensitive(result.Error, a.config.Namespaces)
+	if refs := references(result.Error); len(refs) > 0 {
+		result.Details = strings.Join(append([]string{result.Details}, refs...), "\n")
 	}
 
-	fmt.Printf("ApplicationSet Analyzer: Successfully initialized Kubernetes client\n")
+	runResult := metrics.RunResultError
+	if ok {
+		runResult = metrics.RunResultSuccess
+		if stats.isIncomplete() {
+			runResult = metrics.RunResultIncomplete
+		}
+	}
+	// A failed run keeps the last published counters, except for the clusters that were
+	// still analyzed when others failed
+	if ok || len(stats.clusters) > 0 && len(a.config.KubeContexts) > 0 {
+		stats.publish()
+	}
+	metrics.ObserveRun(start, runResult)
+	logger.Info("Finished analysis", "result", runResult, "applicationSets", stats.applicationSets, "findings", stats.total(), "duration", time.Since(start))
 
-	// List ApplicationSets (either all namespaces or specific namespace)
-	var applicationSets *unstructured.UnstructuredList
-	var err error
+	return &v1.RunResponse{Result: result}, nil // Return nil error here - any error details are in the result
+}
 
-	fmt.Printf("ApplicationSet Analyzer: Listing ApplicationSets in all namespaces\n")
-	applicationSets, err = a.dynamicClient.Resource(applicationSetGVR).List(ctx, metav1.ListOptions{})
+// analyze analyzes the ApplicationSets of the handler's cluster, recording counters in
+// stats. It returns false if the analysis failed and the result only describes the failure.
+func (a *Handler) analyze(ctx context.Context, stats *runStats) (*v1.Result, bool) {
+	logger := logging.FromContext(ctx)
+	scopeMsg := a.scopeMessage()
+	logger.Info("Starting analysis", "scope", scopeMsg)
 
-	if err != nil {
-		fmt.Printf("ApplicationSet Analyzer: Error listing ApplicationSets: %v\n", err)
-		return &v1.RunResponse{
-			Result: &v1.Result{
-				Name:    "applicationset-analyzer",
-				Details: fmt.Sprintf("Failed to list ApplicationSets: %v", err),
-				Error: []*v1.ErrorDetail{
-					{
-						Text: fmt.Sprintf("Error listing ApplicationSets: %v", err),
-					},
-				},
-			},
-		}, nil // Return nil error here - the error details are in the response
-	}
-
-	fmt.Printf("ApplicationSet Analyzer: Found %d ApplicationSets\n", len(applicationSets.Items))
-
-	if len(applicationSets.Items) == 0 {
-		scopeMsg := "in the cluster"
-		return &v1.RunResponse{
-			Result: &v1.Result{
-				Name:    "applicationset-analyzer",
-				Details: fmt.Sprintf("No ApplicationSets found %s", scopeMsg),
-				Error: []*v1.ErrorDetail{
-					{
-						Text: fmt.Sprintf("No ApplicationSets found %s", scopeMsg),
-					},
-				},
+	if err := a.initializeClient(); err != nil {
+		logger.Error("Failed to initialize Kubernetes client", "error", err)
+		return &v1.Result{
+			Kind:    aggregateKind,
+			Name:    resultName,
+			Details: "Failed to initialize Kubernetes client",
+			Error: []*v1.ErrorDetail{
+				finding(rules.ClusterUnreachable, "Could not connect to Kubernetes cluster: %v", err),
 			},
-		}, nil
+		}, false
 	}
 
-	var errors []*v1.ErrorDetail
-	var details []string
+	resources, err := a.servedResources()
+	if err != nil {
+		// Discovery is best effort; fall back to the default versions
+		logger.Warn("Failed to discover Argo CD resources, assuming default versions", "error", err)
+		resources = servedResources{applicationSet: applicationSetGVR, application: applicationGVR}
+	}
+	if resources.applicationSet.Empty() {
+		// A cluster without ApplicationSets has nothing to analyze, which is not a failure
+		logger.Info("ApplicationSet resource is not served by the API server", "group", applicationSetGVR.Group)
+		return &v1.Result{
+			Kind: aggregateKind,
+			Name: resultName,
+			Details: fmt.Sprintf("Argo CD ApplicationSet controller not installed: %s/%s is not served by the API server",
+				applicationSetGVR.Group, applicationSetGVR.Resource),
+		}, true
+	}
+	logger.Debug("Discovered Argo CD resources", "applicationSets", resources.applicationSet.GroupVersion().String(),
+		"applications", resources.application.GroupVersion().String())
 
-	scopeMsg := "in the cluster"
-	details = append(details, fmt.Sprintf("Found %d ApplicationSet(s) %s", len(applicationSets.Items), scopeMsg))
+	var results []appSetResult
 
-	// Analyze each ApplicationSet
-	for _, appSet := range applicationSets.Items {
-		appSetErrors := a.analyzeApplicationSet(ctx, &appSet)
-		errors = append(errors, appSetErrors...)
+	// Preflight: report missing permissions explicitly instead of returning misleadingly clean results
+	// runErrors holds the findings about the run as a whole rather than a single ApplicationSet
+	var runErrors []*v1.ErrorDetail
+	denied, err := a.deniedListAccess(ctx, resources.applicationSet)
+	if err != nil {
+		logger.Warn("Failed to review permissions", "resource", resources.applicationSet.Resource, "error", err)
+	}
+	for _, permission := range denied {
+		runErrors = append(runErrors, finding(rules.MissingPermission, "Missing permission: analyzer %s; ApplicationSet analysis was skipped", permission))
+	}
+	if len(runErrors) > 0 {
+		logger.Warn("Missing permissions to list ApplicationSets, skipping analysis", "denied", denied)
+		stats.addFindings(checkPermissions, "", len(runErrors))
+		return &v1.Result{
+			Kind:    aggregateKind,
+			Name:    resultName,
+			Details: fmt.Sprintf("Missing permissions to analyze ApplicationSets %s", scopeMsg),
+			Error:   runErrors,
+		}, true
+	}
 
-		// Add basic information about the ApplicationSet
-		details = append(details, fmt.Sprintf("ApplicationSet: %s/%s", appSet.GetNamespace(), appSet.GetName()))
+	// Generator checks that cannot read their secrets, ConfigMaps and Services are reported
+	// per generator; the preflight states up front which of them will be skipped
+	if a.config.CheckEnabled(config.CheckGenerators) {
+		denied, err := a.deniedGeneratorAccess(ctx)
+		if err != nil {
+			logger.Warn("Failed to review permissions", "check", config.CheckGenerators, "error", err)
+		}
+		if len(denied) > 0 {
+			logger.Warn("Missing permissions to read generator references, skipping their checks", "denied", denied)
+			for _, permission := range denied {
+				runErrors = append(runErrors, finding(rules.MissingPermission, "Missing permission: analyzer %s; generator checks needing this access were skipped", permission))
+			}
+			stats.addFindings(checkPermissions, "", len(denied))
+		}
+	}
 
-		// Get and display status information
-		status := a.getApplicationSetStatus(&appSet)
-		for _, statusDetail := range status {
-			details = append(details, fmt.Sprintf("  %s", statusDetail))
+	// List Applications once and join them against every ApplicationSet in memory
+	var apps *applicationIndex
+	if a.config.CheckEnabled(config.CheckApplications) {
+		denied, err := a.deniedListAccess(ctx, resources.application)
+		if err != nil {
+			logger.Warn("Failed to review permissions", "resource", resources.application.Resource, "error", err)
+		}
+		if len(denied) > 0 {
+			// Only the live Application checks are skipped; the ApplicationSet's applicationStatus is still analyzed
+			logger.Warn("Missing permissions to list Applications, skipping live Application checks", "denied", denied)
+			for _, permission := range denied {
+				runErrors = append(runErrors, finding(rules.MissingPermission, "Missing permission: analyzer %s; live Application checks were skipped", permission))
+			}
+			stats.addFindings(checkPermissions, "", len(denied))
+			apps = newApplicationIndex()
+			apps.err = fmt.Errorf("missing permissions: %s", strings.Join(denied, ", "))
+		} else {
+			apps = a.buildApplicationIndex(ctx, resources.application)
+			if apps.err != nil {
+				// Don't fail if we can't list applications - the applicationStatus checks are still useful
+				logger.Warn("Failed to list Applications", "error", apps.err)
+			}
 		}
 	}
 
-	result := &v1.Result{
-		Name:    "applicationset-analyzer",
-		Details: strings.Join(details, "\n"),
-		Error:   errors,
+	// Analyze each page of ApplicationSets as it arrives
+	appSetCount := 0
+	logger.Debug("Listing ApplicationSets", "pageSize", a.config.PageSize, "parallelism", a.config.Parallelism, "cached", a.CacheSynced())
+	err = a.forEachApplicationSetPage(ctx, resources.applicationSet, func(page []unstructured.Unstructured) error {
+		logger.Debug("Analyzing page of ApplicationSets", "count", len(page))
+		appSetCount += len(page)
+		stats.addApplicationSets(len(page))
+		results = append(results, a.analyzeApplicationSets(ctx, page, apps, stats)...)
+		return nil
+	})
+
+	// A real client fails the list once the Run deadline expires, which is reported as the
+	// expired deadline along with whatever was analyzed, not as a failure of the API server
+	deadlineExceeded := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if err != nil && !deadlineExceeded {
+		logger.Error("Failed to list ApplicationSets", "error", err)
+		return &v1.Result{
+			Kind:    aggregateKind,
+			Name:    resultName,
+			Details: fmt.Sprintf("Failed to list ApplicationSets: %v", err),
+			Error: []*v1.ErrorDetail{
+				finding(rules.ListFailed, "Error listing ApplicationSets: %v", err),
+			},
+		}, false
+	}
+
+	if appSetCount == 0 && !deadlineExceeded {
+		// Report the empty scope, which often means a wrong namespace or selector
+		return &v1.Result{
+			Kind:    aggregateKind,
+			Name:    resultName,
+			Details: fmt.Sprintf("No ApplicationSets found %s", scopeMsg),
+			Error:   append(runErrors, finding(rules.NoApplicationSets, "No ApplicationSets found %s", scopeMsg)),
+		}, true
 	}
 
-	return &v1.RunResponse{Result: result}, nil
+	if deadlineExceeded {
+		logger.Warn("Run deadline exceeded, results may be incomplete", "error", err)
+		runErrors = append(runErrors, finding(rules.RunDeadlineExceeded, "The deadline of the Run request expired before the analysis completed; results may be incomplete"))
+	}
+
+	// Merge in namespace/name order so the response does not depend on scheduling
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].namespace != results[j].namespace {
+			return results[i].namespace < results[j].namespace
+		}
+		return results[i].name < results[j].name
+	})
+
+	return a.mergeResults(fmt.Sprintf("Found %d ApplicationSet(s) %s", appSetCount, scopeMsg), runErrors, results), true
 }
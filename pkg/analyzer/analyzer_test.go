@@ -1,16 +1,39 @@
 package analyzer
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/config"
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/dynamic/fake"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestAnalyzer_Run_BasicFunctionality(t *testing.T) {
@@ -87,16 +110,18 @@ func TestAnalyzer_Run_BasicFunctionality(t *testing.T) {
 
 	// Verify the response
 	assert.Equal(t, "applicationset-analyzer", response.Result.Name)
+	assert.Equal(t, "ApplicationSetList", response.Result.Kind)
+	assert.Empty(t, response.Result.ParentObject, "a result covering several ApplicationSets has no single parent")
 	assert.Contains(t, response.Result.Details, "Found 2 ApplicationSet(s) in the cluster")
 
 	// Check that errors were detected
 	foundErrorCondition := false
 	foundNoGenerators := false
 	for _, err := range response.Result.Error {
-		if err.Text == "ApplicationSet default/test-appset-1 has error condition: Test error message" {
+		if err.Text == "[ASA001/critical] ApplicationSet default/test-appset-1 has error condition: Test error message" {
 			foundErrorCondition = true
 		}
-		if err.Text == "ApplicationSet default/test-appset-2 has no generators defined" {
+		if err.Text == "[ASA006/critical] ApplicationSet default/test-appset-2 has no generators defined" {
 			foundNoGenerators = true
 		}
 	}
@@ -160,10 +185,10 @@ func TestAnalyzer_Run_ProgressingState(t *testing.T) {
 	foundProgressing := false
 	foundParameterGeneration := false
 	for _, err := range response.Result.Error {
-		if err.Text == "ApplicationSet default/progressing-appset is in progressing state: ApplicationSet is progressing" {
+		if err.Text == "[ASA004/warning] ApplicationSet default/progressing-appset is in progressing state: ApplicationSet is progressing" {
 			foundProgressing = true
 		}
-		if err.Text == "ApplicationSet default/progressing-appset failed to generate parameters: Failed to generate parameters" {
+		if err.Text == "[ASA002/critical] ApplicationSet default/progressing-appset failed to generate parameters: Failed to generate parameters" {
 			foundParameterGeneration = true
 		}
 	}
@@ -230,13 +255,13 @@ func TestAnalyzer_Run_GeneratorValidation(t *testing.T) {
 
 	for _, err := range response.Result.Error {
 		switch err.Text {
-		case "ApplicationSet default/bad-generators-appset has empty generator at index 0":
+		case "[ASA008/critical] ApplicationSet default/bad-generators-appset has empty generator at index 0":
 			foundEmptyGenerator = true
-		case "ApplicationSet default/bad-generators-appset Git generator at index 1 has empty repoURL":
+		case "[ASA009/critical] ApplicationSet default/bad-generators-appset Git generator at index 1 has empty repoURL":
 			foundEmptyRepoURL = true
-		case "ApplicationSet default/bad-generators-appset List generator at index 2 has empty elements array":
+		case "[ASA011/warning] ApplicationSet default/bad-generators-appset List generator at index 2 has empty elements array":
 			foundEmptyElements = true
-		case "ApplicationSet default/bad-generators-appset Cluster generator at index 3 has no selector or values":
+		case "[ASA012/info] ApplicationSet default/bad-generators-appset Cluster generator at index 3 has no selector or values":
 			foundNoSelectorOrValues = true
 		}
 	}
@@ -245,6 +270,11 @@ func TestAnalyzer_Run_GeneratorValidation(t *testing.T) {
 	assert.True(t, foundEmptyRepoURL, "Should detect empty repoURL")
 	assert.True(t, foundEmptyElements, "Should detect empty elements array")
 	assert.True(t, foundNoSelectorOrValues, "Should detect missing selector or values")
+
+	// Field-level findings are grounded in the generator reference and schema
+	assert.Contains(t, response.Result.Details, "\n  ASA009 spec.generators[].git.repoURL: https://argo-cd.readthedocs.io/en/stable/operator-manual/applicationset/Generators-Git/\n"+
+		"    repoURL <string> -required-\n"+
+		"      URL of the Git repository whose directories or files generate the parameters.")
 }
 
 func TestAnalyzer_Run_GeneratedApplicationsStatus(t *testing.T) {
@@ -321,10 +351,35 @@ func TestAnalyzer_Run_GeneratedApplicationsStatus(t *testing.T) {
 		},
 	}
 
+	// The ApplicationSet status still reports test-app-prod as OutOfSync, but it has synced since
+	syncedApp := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Application",
+			"metadata": map[string]interface{}{
+				"name":      "test-app-prod",
+				"namespace": "default",
+				"labels": map[string]interface{}{
+					"argocd.argoproj.io/application-set-name": "appset-with-apps",
+				},
+			},
+			"status": map[string]interface{}{
+				"health": map[string]interface{}{
+					"status": "Healthy",
+				},
+				"sync": map[string]interface{}{
+					"status": "Synced",
+				},
+			},
+		},
+	}
+
 	_, err := client.Resource(applicationSetGVR).Namespace("default").Create(context.TODO(), appSetWithApps, metav1.CreateOptions{})
 	assert.NoError(t, err)
-	_, err = client.Resource(applicationGVR).Namespace("default").Create(context.TODO(), generatedApp, metav1.CreateOptions{})
-	assert.NoError(t, err)
+	for _, app := range []*unstructured.Unstructured{generatedApp, syncedApp} {
+		_, err = client.Resource(applicationGVR).Namespace("default").Create(context.TODO(), app, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
 
 	analyzer := NewAnalyzer().WithDynamicClient(client)
 	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
@@ -332,33 +387,58 @@ func TestAnalyzer_Run_GeneratedApplicationsStatus(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, response.Result)
 
-	// Check for application status issues
-	foundUnhealthyApp := false
-	foundOutOfSyncApp1 := false
-	foundOutOfSyncApp2 := false
-	foundFailedOperation := false
-
-	for _, err := range response.Result.Error {
-		switch {
-		case err.Text == "Generated Application test-app-dev is not healthy (status: Degraded): Application is unhealthy":
-			foundUnhealthyApp = true
-		case err.Text == "Generated Application test-app-dev is not synced (status: OutOfSync)":
-			foundOutOfSyncApp1 = true
-		case err.Text == "Generated Application test-app-prod is not synced (status: OutOfSync)":
-			foundOutOfSyncApp2 = true
-		case err.Text == "Application default/test-app-dev has failed operation: Sync operation failed":
-			foundFailedOperation = true
-		}
+	// Each problem is reported once, from the live Application, citing the ApplicationSet status
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
 	}
+	assert.ElementsMatch(t, []string{
+		"[ASA017/critical] Application default/test-app-dev is not healthy (status: Degraded, also reported by the ApplicationSet status): Pod is failing",
+		"[ASA018/warning] Application default/test-app-dev is not synced (status: OutOfSync, also reported by the ApplicationSet status)",
+		"[ASA019/critical] Application default/test-app-dev has failed operation: Sync operation failed",
+		"[ASA024/info] ApplicationSet status reports sync OutOfSync for Application default/test-app-prod, but the live Application is Synced",
+	}, texts)
 
-	assert.True(t, foundUnhealthyApp, "Should detect unhealthy application")
-	assert.True(t, foundOutOfSyncApp1, "Should detect out of sync application (from status)")
-	assert.True(t, foundOutOfSyncApp2, "Should detect out of sync application (second app)")
-	assert.True(t, foundFailedOperation, "Should detect failed operation")
+	// Without the live Applications the ApplicationSet status is reported on its own
+	client = fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	_, err = client.Resource(applicationSetGVR).Namespace("default").Create(context.TODO(), appSetWithApps, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	response, err = NewAnalyzer().WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	texts = nil
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA014/critical] Generated Application test-app-dev is not healthy (status: Degraded): Application is unhealthy",
+		"[ASA015/warning] Generated Application test-app-dev is not synced (status: OutOfSync)",
+		"[ASA015/warning] Generated Application test-app-prod is not synced (status: OutOfSync)",
+	}, texts)
 
 	// Check that status details are included
 	assert.Contains(t, response.Result.Details, "Generated Applications: 2")
 	assert.Contains(t, response.Result.Details, "App: test-app-dev (Health: Degraded, Sync: OutOfSync)")
+
+	// A live Application without a status yet is reported from the ApplicationSet status
+	client = fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	_, err = client.Resource(applicationSetGVR).Namespace("default").Create(context.TODO(), appSetWithApps, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	newApp := generatedApp.DeepCopy()
+	unstructured.RemoveNestedField(newApp.Object, "status")
+	_, err = client.Resource(applicationGVR).Namespace("default").Create(context.TODO(), newApp, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	response, err = NewAnalyzer().WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	texts = nil
+	for _, e := range response.Result.Error {
+		if strings.Contains(e.Text, "test-app-dev") {
+			texts = append(texts, e.Text)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA017/critical] Application default/test-app-dev is not healthy (status: Degraded, reported by the ApplicationSet status; the live Application has no status yet): Application is unhealthy",
+		"[ASA018/warning] Application default/test-app-dev is not synced (status: OutOfSync, reported by the ApplicationSet status; the live Application has no status yet)",
+	}, texts)
 }
 
 func TestAnalyzer_Run_NoApplicationSets(t *testing.T) {
@@ -376,7 +456,9 @@ func TestAnalyzer_Run_NoApplicationSets(t *testing.T) {
 	assert.NotNil(t, response.Result)
 	assert.Equal(t, "applicationset-analyzer", response.Result.Name)
 	assert.Equal(t, "No ApplicationSets found in the cluster", response.Result.Details)
-	assert.Empty(t, response.Result.Error)
+	if assert.Len(t, response.Result.Error, 1) {
+		assert.Equal(t, "[ASA047/info] No ApplicationSets found in the cluster", response.Result.Error[0].Text)
+	}
 }
 
 func TestAnalyzer_Run_HealthyApplicationSet(t *testing.T) {
@@ -440,3 +522,2064 @@ func TestAnalyzer_Run_HealthyApplicationSet(t *testing.T) {
 	// Should have no errors for healthy ApplicationSet
 	assert.Empty(t, response.Result.Error, "Healthy ApplicationSet should have no errors")
 }
+
+func TestAnalyzer_Run_WithConfig(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	for _, namespace := range []string{"argocd", "other"} {
+		appSet := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "argoproj.io/v1alpha1",
+				"kind":       "ApplicationSet",
+				"metadata": map[string]interface{}{
+					"name":      "appset",
+					"namespace": namespace,
+				},
+				"spec": map[string]interface{}{
+					"generators": []interface{}{},
+				},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{
+							"type":    "ErrorOccurred",
+							"status":  "True",
+							"message": "Test error message",
+						},
+					},
+				},
+			},
+		}
+		_, err := client.Resource(applicationSetGVR).Namespace(namespace).Create(context.TODO(), appSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	cfg := config.Default()
+	cfg.Namespaces = []string{"argocd"}
+	cfg.Checks.Enabled = []string{config.CheckConditions}
+
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(cfg)
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, response.Result)
+	assert.Contains(t, response.Result.Details, "Found 1 ApplicationSet(s) in namespace(s) argocd")
+
+	// Only the conditions check runs, so the missing generators are not reported
+	if assert.Len(t, response.Result.Error, 1) {
+		assert.Equal(t, "[ASA001/critical] ApplicationSet argocd/appset has error condition: Test error message", response.Result.Error[0].Text)
+	}
+}
+
+func TestHandler_Ping(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithDiscoveryClient(discoveryClient)
+	assert.NoError(t, analyzer.Handler.Ping(context.TODO()))
+
+	discoveryClient.PrependReactor("get", "version", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	assert.ErrorContains(t, analyzer.Handler.Ping(context.TODO()), "failed to reach Kubernetes API server")
+}
+
+func TestAnalyzer_Run_Metrics(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	appSet := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "ApplicationSet",
+			"metadata": map[string]interface{}{
+				"name":      "metrics-appset",
+				"namespace": "argocd",
+			},
+			"spec": map[string]interface{}{
+				"generators": []interface{}{},
+			},
+		},
+	}
+	app := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Application",
+			"metadata": map[string]interface{}{
+				"name":      "metrics-app",
+				"namespace": "argocd",
+				"labels": map[string]interface{}{
+					"argocd.argoproj.io/application-set-name": "metrics-appset",
+				},
+			},
+		},
+	}
+	_, err := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = client.Resource(applicationGVR).Namespace("argocd").Create(context.TODO(), app, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	runsBefore := testutil.ToFloat64(metrics.RunsTotal.WithLabelValues(metrics.RunResultSuccess))
+	listsBefore := testutil.ToFloat64(metrics.APIRequestsTotal.WithLabelValues("argoproj.io", "v1alpha1", "applications", "list"))
+
+	analyzer := NewAnalyzer().WithDynamicClient(client)
+	_, err = analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, runsBefore+1, testutil.ToFloat64(metrics.RunsTotal.WithLabelValues(metrics.RunResultSuccess)))
+	assert.Equal(t, listsBefore+1, testutil.ToFloat64(metrics.APIRequestsTotal.WithLabelValues("argoproj.io", "v1alpha1", "applications", "list")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ApplicationSetsScanned))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.ApplicationsScanned))
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.Findings.WithLabelValues("", config.CheckGenerators, "argocd")))
+}
+
+func TestAnalyzer_Run_Logging(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	appSet := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "ApplicationSet",
+			"metadata": map[string]interface{}{
+				"name":      "logged-appset",
+				"namespace": "argocd",
+			},
+			"spec": map[string]interface{}{
+				"generators": []interface{}{},
+			},
+		},
+	}
+	_, err := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, "debug", logging.FormatJSON)
+	assert.NoError(t, err)
+
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithLogger(logger)
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs("x-request-id", "req-42"))
+	_, err = analyzer.Handler.Run(ctx, &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	foundAppSetRecord := false
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &record))
+		assert.Equal(t, "req-42", record["requestID"], "every record should carry the request ID")
+		if record["applicationSet"] == "logged-appset" && record["namespace"] == "argocd" {
+			foundAppSetRecord = true
+		}
+	}
+	assert.True(t, foundAppSetRecord, "Should log ApplicationSet namespace and name")
+}
+
+func TestAnalyzer_Run_Selectors(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	for _, team := range []string{"a", "b"} {
+		appSet := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "argoproj.io/v1alpha1",
+				"kind":       "ApplicationSet",
+				"metadata": map[string]interface{}{
+					"name":      "appset-" + team,
+					"namespace": "argocd",
+					"labels": map[string]interface{}{
+						"team": team,
+					},
+				},
+				"spec": map[string]interface{}{
+					"generators": []interface{}{
+						map[string]interface{}{
+							"list": map[string]interface{}{
+								"elements": []interface{}{
+									map[string]interface{}{"env": "prod"},
+								},
+							},
+						},
+					},
+				},
+				// The backend Application is excluded by the Application selector and
+				// must not be reported from the ApplicationSet status either
+				"status": map[string]interface{}{
+					"applicationStatus": []interface{}{
+						map[string]interface{}{
+							"application": fmt.Sprintf("app-%s-backend", team),
+							"health":      "Degraded",
+							"sync":        "OutOfSync",
+						},
+					},
+				},
+			},
+		}
+		_, err := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+
+		for _, tier := range []string{"frontend", "backend"} {
+			app := &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "argoproj.io/v1alpha1",
+					"kind":       "Application",
+					"metadata": map[string]interface{}{
+						"name":      fmt.Sprintf("app-%s-%s", team, tier),
+						"namespace": "argocd",
+						"labels": map[string]interface{}{
+							"argocd.argoproj.io/application-set-name": "appset-" + team,
+							"tier": tier,
+						},
+					},
+					"status": map[string]interface{}{
+						"sync": map[string]interface{}{
+							"status": "OutOfSync",
+						},
+					},
+				},
+			}
+			_, err := client.Resource(applicationGVR).Namespace("argocd").Create(context.TODO(), app, metav1.CreateOptions{})
+			assert.NoError(t, err)
+		}
+	}
+
+	// Record the options of every list call
+	var listOptions []metav1.ListOptions
+	client.PrependReactor("list", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		listAction := action.(k8stesting.ListActionImpl)
+		listOptions = append(listOptions, metav1.ListOptions{
+			LabelSelector: listAction.GetListRestrictions().Labels.String(),
+			FieldSelector: listAction.GetListRestrictions().Fields.String(),
+		})
+		return false, nil, nil
+	})
+
+	cfg := config.Default()
+	cfg.Namespaces = []string{"argocd"}
+	cfg.LabelSelector = "team=a"
+	cfg.FieldSelector = "metadata.namespace=argocd"
+	cfg.ApplicationLabelSelector = "tier=frontend"
+
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(cfg)
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, response.Result)
+	assert.Contains(t, response.Result.Details,
+		`Found 1 ApplicationSet(s) in namespace(s) argocd matching labels "team=a" and fields "metadata.namespace=argocd" (Applications matching labels "tier=frontend")`)
+	assert.NotContains(t, response.Result.Details, "appset-b")
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.Equal(t, []string{"[ASA018/warning] Application argocd/app-a-frontend is not synced (status: OutOfSync)"}, texts)
+
+	// Applications are listed once up front, then joined to the ApplicationSets in memory
+	if assert.Len(t, listOptions, 2) {
+		assert.Equal(t, "tier=frontend", listOptions[0].LabelSelector)
+		assert.Empty(t, listOptions[0].FieldSelector)
+		assert.Equal(t, "team=a", listOptions[1].LabelSelector)
+		assert.Equal(t, "metadata.namespace=argocd", listOptions[1].FieldSelector)
+	}
+}
+
+// pagingClient wraps a fake dynamic client and splits List results into pages
+// honoring Limit and Continue, which the fake client ignores. It is safe for
+// concurrent use and can run a hook before every List call.
+type pagingClient struct {
+	dynamic.Interface
+	beforeList func(ctx context.Context, resource string) error
+
+	mu    sync.Mutex
+	calls map[string][]metav1.ListOptions
+}
+
+func newPagingClient(client dynamic.Interface) *pagingClient {
+	return &pagingClient{Interface: client, calls: make(map[string][]metav1.ListOptions)}
+}
+
+func (c *pagingClient) Resource(gvr schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &pagingResource{NamespaceableResourceInterface: c.Interface.Resource(gvr), client: c, resource: gvr.Resource}
+}
+
+type pagingResource struct {
+	dynamic.NamespaceableResourceInterface
+	client   *pagingClient
+	resource string
+}
+
+func (r *pagingResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &pagingNamespacedResource{ResourceInterface: r.NamespaceableResourceInterface.Namespace(namespace), parent: r}
+}
+
+func (r *pagingResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return r.page(ctx, r.NamespaceableResourceInterface, opts)
+}
+
+type pagingNamespacedResource struct {
+	dynamic.ResourceInterface
+	parent *pagingResource
+}
+
+func (r *pagingNamespacedResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return r.parent.page(ctx, r.ResourceInterface, opts)
+}
+
+func (r *pagingResource) page(ctx context.Context, ri dynamic.ResourceInterface, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	r.client.mu.Lock()
+	r.client.calls[r.resource] = append(r.client.calls[r.resource], opts)
+	r.client.mu.Unlock()
+
+	if r.client.beforeList != nil {
+		if err := r.client.beforeList(ctx, r.resource); err != nil {
+			return nil, err
+		}
+	}
+
+	all := opts
+	all.Limit, all.Continue = 0, ""
+	list, err := ri.List(ctx, all)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := 0
+	if opts.Continue != "" {
+		offset, _ = strconv.Atoi(opts.Continue)
+	}
+	end := len(list.Items)
+	list.SetContinue("")
+	if opts.Limit > 0 && offset+int(opts.Limit) < end {
+		end = offset + int(opts.Limit)
+		list.SetContinue(strconv.Itoa(end))
+	}
+	list.Items = list.Items[offset:end]
+	return list, nil
+}
+
+func TestAnalyzer_Run_Pagination(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	for i := 0; i < 5; i++ {
+		appSetName := fmt.Sprintf("appset-%d", i)
+		appSet := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "argoproj.io/v1alpha1",
+				"kind":       "ApplicationSet",
+				"metadata": map[string]interface{}{
+					"name":      appSetName,
+					"namespace": "argocd",
+				},
+				"spec": map[string]interface{}{
+					"generators": []interface{}{
+						map[string]interface{}{
+							"list": map[string]interface{}{
+								"elements": []interface{}{
+									map[string]interface{}{"env": "prod"},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+		_, err := fakeClient.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+
+		for j := 0; j < 3; j++ {
+			app := &unstructured.Unstructured{
+				Object: map[string]interface{}{
+					"apiVersion": "argoproj.io/v1alpha1",
+					"kind":       "Application",
+					"metadata": map[string]interface{}{
+						"name":      fmt.Sprintf("%s-app-%d", appSetName, j),
+						"namespace": "argocd",
+						"labels": map[string]interface{}{
+							"argocd.argoproj.io/application-set-name": appSetName,
+						},
+					},
+					"status": map[string]interface{}{
+						"sync": map[string]interface{}{
+							"status": "OutOfSync",
+						},
+					},
+				},
+			}
+			_, err := fakeClient.Resource(applicationGVR).Namespace("argocd").Create(context.TODO(), app, metav1.CreateOptions{})
+			assert.NoError(t, err)
+		}
+	}
+
+	client := newPagingClient(fakeClient)
+	cfg := config.Default()
+	cfg.PageSize = 2
+
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(cfg)
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, response.Result)
+	assert.Contains(t, response.Result.Details, "Found 5 ApplicationSet(s) in the cluster")
+	for i := 0; i < 5; i++ {
+		assert.Contains(t, response.Result.Details, fmt.Sprintf("ApplicationSet: argocd/appset-%d", i))
+	}
+	assert.Len(t, response.Result.Error, 15, "every Application on every page should be analyzed")
+
+	// 5 ApplicationSets in pages of 2
+	appSetCalls := client.calls["applicationsets"]
+	if assert.Len(t, appSetCalls, 3) {
+		assert.Equal(t, []string{"", "2", "4"}, []string{appSetCalls[0].Continue, appSetCalls[1].Continue, appSetCalls[2].Continue})
+		for _, call := range appSetCalls {
+			assert.Equal(t, int64(2), call.Limit)
+		}
+	}
+	// One cluster-wide list of 15 Applications in pages of 2
+	assert.Len(t, client.calls["applications"], 8)
+}
+
+// newTestApplicationSet returns an ApplicationSet with an error condition and one list generator
+func newTestApplicationSet(namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "ApplicationSet",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"generators": []interface{}{
+					map[string]interface{}{
+						"list": map[string]interface{}{
+							"elements": []interface{}{
+								map[string]interface{}{"env": "prod"},
+							},
+						},
+					},
+				},
+			},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":    "ErrorOccurred",
+						"status":  "True",
+						"message": "broken",
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestAnalyzer_Run_Concurrency(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	var expected []string
+	for _, namespace := range []string{"team-b", "team-a"} {
+		for i := 9; i >= 0; i-- {
+			appSet := newTestApplicationSet(namespace, fmt.Sprintf("appset-%d", i))
+			_, err := fakeClient.Resource(applicationSetGVR).Namespace(namespace).Create(context.TODO(), appSet, metav1.CreateOptions{})
+			assert.NoError(t, err)
+		}
+	}
+	for _, namespace := range []string{"team-a", "team-b"} {
+		for i := 0; i < 10; i++ {
+			expected = append(expected, fmt.Sprintf("[ASA001/critical] ApplicationSet %s/appset-%d has error condition: broken", namespace, i))
+		}
+	}
+
+	client := newPagingClient(fakeClient)
+	cfg := config.Default()
+	cfg.PageSize = 7
+	cfg.Parallelism = 3
+	cfg.Checks.Enabled = []string{config.CheckConditions, config.CheckApplications}
+
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(cfg)
+	for run := 0; run < 3; run++ {
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+
+		var texts []string
+		for _, e := range response.Result.Error {
+			if strings.Contains(e.Text, "error condition") {
+				texts = append(texts, e.Text)
+			}
+		}
+		assert.Equal(t, expected, texts, "findings should be merged in namespace/name order")
+		assert.Contains(t, response.Result.Details, "Found 20 ApplicationSet(s) in the cluster")
+	}
+}
+
+func TestAnalyzer_Run_ApplicationSetTimeout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	_, err := fakeClient.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), newTestApplicationSet("argocd", "slow"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// Listing ApplicationSets and Applications happens under the Run context and is
+	// unaffected by the per-ApplicationSet deadline
+	client := newPagingClient(fakeClient)
+	client.beforeList = func(ctx context.Context, resource string) error {
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline, "list of %s should not use the per-ApplicationSet deadline", resource)
+		return nil
+	}
+
+	// A deadline that has always expired by the time the analysis finishes
+	cfg := config.Default()
+	cfg.ApplicationSetTimeout = metav1.Duration{Duration: time.Nanosecond}
+
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(cfg)
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	found := false
+	for _, e := range response.Result.Error {
+		if e.Text == "[ASA020/warning] Analysis of ApplicationSet argocd/slow timed out after 1ns; results may be incomplete" {
+			found = true
+		}
+	}
+	assert.True(t, found, "Should report the timed out ApplicationSet")
+
+	// An expired Run deadline is reported once for the run rather than blamed on each ApplicationSet
+	client.beforeList = func(ctx context.Context, resource string) error {
+		if resource == "applications" {
+			<-ctx.Done()
+		}
+		return nil
+	}
+	cfg.ApplicationSetTimeout = metav1.Duration{Duration: time.Minute}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	response, err = analyzer.Handler.Run(ctx, &v1.RunRequest{})
+	assert.NoError(t, err)
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.Contains(t, texts, "[ASA046/warning] The deadline of the Run request expired before the analysis completed; results may be incomplete")
+	for _, text := range texts {
+		assert.NotContains(t, text, "ASA020", "the ApplicationSet should not be blamed for the Run deadline")
+	}
+	// A real client fails the ApplicationSet list with the expired context
+	client.beforeList = func(ctx context.Context, resource string) error {
+		if resource == "applicationsets" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	response, err = analyzer.Handler.Run(ctx, &v1.RunRequest{})
+	assert.NoError(t, err)
+	texts = nil
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.Equal(t, []string{"[ASA046/warning] The deadline of the Run request expired before the analysis completed; results may be incomplete"}, texts)
+}
+
+func TestAnalyzer_Run_ApplicationJoin(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	for _, name := range []string{"owner", "labelled"} {
+		appSet := newTestApplicationSet("argocd", name)
+		appSet.SetUID(types.UID(name + "-uid"))
+		_, err := fakeClient.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	newApp := func(name string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "argoproj.io/v1alpha1",
+				"kind":       "Application",
+				"metadata": map[string]interface{}{
+					"name":      name,
+					"namespace": "argocd",
+				},
+				"status": map[string]interface{}{
+					"sync": map[string]interface{}{
+						"status": "OutOfSync",
+					},
+				},
+			},
+		}
+	}
+
+	// Owned by an ApplicationSet but without the ApplicationSet name label
+	owned := newApp("owned-app")
+	owned.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "argoproj.io/v1alpha1",
+		Kind:       "ApplicationSet",
+		Name:       "owner",
+		UID:        "owner-uid",
+	}})
+	// Labelled and owned by the same ApplicationSet, reported once
+	labelled := newApp("labelled-app")
+	labelled.SetLabels(map[string]string{applicationSetNameLabel: "labelled"})
+	labelled.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: "argoproj.io/v1alpha1",
+		Kind:       "ApplicationSet",
+		Name:       "labelled",
+		UID:        "labelled-uid",
+	}})
+	// Not generated by any ApplicationSet
+	standalone := newApp("standalone-app")
+
+	for _, app := range []*unstructured.Unstructured{owned, labelled, standalone} {
+		_, err := fakeClient.Resource(applicationGVR).Namespace("argocd").Create(context.TODO(), app, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	client := newPagingClient(fakeClient)
+	analyzer := NewAnalyzer().WithDynamicClient(client)
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		if strings.HasPrefix(e.Text, "[ASA018/warning] Application ") {
+			texts = append(texts, e.Text)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA018/warning] Application argocd/labelled-app is not synced (status: OutOfSync)",
+		"[ASA018/warning] Application argocd/owned-app is not synced (status: OutOfSync)",
+	}, texts)
+	assert.Len(t, client.calls["applications"], 1, "Applications should be listed once per run")
+}
+
+func TestAnalyzer_Run_WatchMode(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	_, err := fakeClient.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), newTestApplicationSet("argocd", "first"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// The fake client drops events for objects created before a watch is
+	// established, so wait for both informers to start watching
+	watches := make(chan string, 2)
+	fakeClient.PrependWatchReactor("*", func(action k8stesting.Action) (bool, watch.Interface, error) {
+		watches <- action.GetResource().Resource
+		return false, nil, nil
+	})
+
+	var reviews atomic.Int32
+	clientset := kubefake.NewSimpleClientset()
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews.Add(1)
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = true
+		return true, review, nil
+	})
+
+	client := newPagingClient(fakeClient)
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithAuthorizationClient(clientset.AuthorizationV1())
+	assert.False(t, analyzer.Handler.CacheSynced())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, analyzer.Handler.StartCache(ctx))
+	assert.Eventually(t, analyzer.Handler.CacheSynced, 5*time.Second, 10*time.Millisecond)
+	for i := 0; i < 2; i++ {
+		select {
+		case <-watches:
+		case <-time.After(5 * time.Second):
+			t.Fatal("informers did not start watching")
+		}
+	}
+	assert.NoError(t, analyzer.Handler.Ping(ctx))
+
+	for run := 0; run < 3; run++ {
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		assert.Contains(t, response.Result.Details, "Found 1 ApplicationSet(s) in the cluster")
+	}
+	// Only the informers' initial lists reach the API server
+	assert.Len(t, client.calls["applicationsets"], 1)
+	assert.Len(t, client.calls["applications"], 1)
+	// and permissions are reviewed once, not on every Run
+	firstReviews := reviews.Load()
+	assert.NotZero(t, firstReviews)
+	_, err = analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, firstReviews, reviews.Load())
+
+	// Changes are picked up from the watch without listing again
+	_, err = fakeClient.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), newTestApplicationSet("argocd", "second"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		return err == nil && strings.Contains(response.Result.Details, "ApplicationSet: argocd/second")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Len(t, client.calls["applicationsets"], 1)
+}
+
+func TestAnalyzer_StartCache_NotInstalled(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := newPagingClient(fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds))
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{
+		Resources: []*metav1.APIResourceList{{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod"}},
+		}},
+	}}
+
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithDiscoveryClient(discoveryClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, analyzer.Handler.StartCache(ctx))
+
+	// Without informers waiting for a missing CRD the handler stays healthy
+	assert.NoError(t, analyzer.Handler.Ping(ctx))
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Argo CD ApplicationSet controller not installed: argoproj.io/applicationsets is not served by the API server", response.Result.Details)
+	assert.Empty(t, client.calls, "nothing should be listed or watched")
+}
+
+func TestAnalyzer_StartCache_ApplicationsNotServed(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	_, err := fakeClient.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), newTestApplicationSet("argocd", "guestbook"), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{
+		Resources: []*metav1.APIResourceList{{
+			GroupVersion: "argoproj.io/v1alpha1",
+			APIResources: []metav1.APIResource{{Name: "applicationsets", Namespaced: true, Kind: "ApplicationSet"}},
+		}},
+	}}
+
+	client := newPagingClient(fakeClient)
+	analyzer := NewAnalyzer().WithDynamicClient(client).WithDiscoveryClient(discoveryClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, analyzer.Handler.StartCache(ctx))
+
+	// Only the ApplicationSets are watched, so the cache syncs and the handler becomes healthy
+	assert.Eventually(t, analyzer.Handler.CacheSynced, 5*time.Second, 10*time.Millisecond)
+	assert.NoError(t, analyzer.Handler.Ping(ctx))
+	assert.Empty(t, client.calls["applications"], "Applications should not be watched")
+
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	assert.Contains(t, response.Result.Details, "Found 1 ApplicationSet(s) in the cluster")
+}
+
+func TestAnalyzer_Run_ResourceDiscovery(t *testing.T) {
+	v1beta1AppSetGVR := schema.GroupVersionResource{Group: "argoproj.io", Version: "v1beta1", Resource: "applicationsets"}
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+		v1beta1AppSetGVR:  "ApplicationSetList",
+	}
+
+	t.Run("not installed", func(t *testing.T) {
+		client := newPagingClient(fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds))
+		discoveryClient := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{
+			Resources: []*metav1.APIResourceList{{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{{Name: "pods", Namespaced: true, Kind: "Pod"}},
+			}},
+		}}
+
+		analyzer := NewAnalyzer().WithDynamicClient(client).WithDiscoveryClient(discoveryClient)
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, "Argo CD ApplicationSet controller not installed: argoproj.io/applicationsets is not served by the API server", response.Result.Details)
+		assert.Empty(t, response.Result.Error)
+		assert.Empty(t, client.calls, "nothing should be listed")
+
+		// The CRDs are discovered once installed
+		discoveryClient.Resources = append(discoveryClient.Resources, &metav1.APIResourceList{
+			GroupVersion: "argoproj.io/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "applicationsets", Namespaced: true, Kind: "ApplicationSet"},
+				{Name: "applications", Namespaced: true, Kind: "Application"},
+			},
+		})
+		response, err = analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		assert.Contains(t, response.Result.Details, "No ApplicationSets found")
+	})
+
+	t.Run("served version", func(t *testing.T) {
+		fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+		appSet := newTestApplicationSet("argocd", "beta")
+		appSet.SetAPIVersion("argoproj.io/v1beta1")
+		_, err := fakeClient.Resource(v1beta1AppSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+
+		// Only ApplicationSets are served, in a version other than the default
+		discoveryClient := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{
+			Resources: []*metav1.APIResourceList{{
+				GroupVersion: "argoproj.io/v1beta1",
+				APIResources: []metav1.APIResource{{Name: "applicationsets", Namespaced: true, Kind: "ApplicationSet"}},
+			}},
+		}}
+
+		client := newPagingClient(fakeClient)
+		analyzer := NewAnalyzer().WithDynamicClient(client).WithDiscoveryClient(discoveryClient)
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		assert.Contains(t, response.Result.Details, "ApplicationSet: argocd/beta")
+		assert.Len(t, client.calls["applicationsets"], 1)
+		assert.Empty(t, client.calls["applications"], "Applications are not served and should not be listed")
+	})
+}
+
+func TestAnalyzer_Run_PermissionPreflight(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+
+	// newAuthorizationClient allows everything except the given resources
+	newAuthorizationClient := func(deniedResources ...string) *kubefake.Clientset {
+		clientset := kubefake.NewSimpleClientset()
+		clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+			review.Status.Allowed = true
+			for _, resource := range deniedResources {
+				if review.Spec.ResourceAttributes.Resource == resource {
+					review.Status.Allowed = false
+				}
+			}
+			return true, review, nil
+		})
+		return clientset
+	}
+
+	newClient := func() *pagingClient {
+		fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+		appSet := newTestApplicationSet("argocd", "guestbook")
+		assert.NoError(t, unstructured.SetNestedSlice(appSet.Object, []interface{}{
+			map[string]interface{}{"application": "guestbook-dev", "sync": "OutOfSync"},
+		}, "status", "applicationStatus"))
+		_, err := fakeClient.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+		return newPagingClient(fakeClient)
+	}
+
+	errorTexts := func(response *v1.RunResponse) []string {
+		var texts []string
+		for _, e := range response.Result.Error {
+			texts = append(texts, e.Text)
+		}
+		return texts
+	}
+
+	t.Run("applicationsets denied", func(t *testing.T) {
+		client := newClient()
+		cfg := config.Default()
+		cfg.Namespaces = []string{"argocd", "team-a"}
+		analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(cfg).
+			WithAuthorizationClient(newAuthorizationClient("applicationsets").AuthorizationV1())
+
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{
+			"[ASA021/critical] Missing permission: analyzer cannot list applicationsets.argoproj.io in namespace argocd; ApplicationSet analysis was skipped",
+			"[ASA021/critical] Missing permission: analyzer cannot list applicationsets.argoproj.io in namespace team-a; ApplicationSet analysis was skipped",
+		}, errorTexts(response))
+		assert.Empty(t, client.calls, "nothing should be listed")
+	})
+
+	t.Run("applications denied", func(t *testing.T) {
+		client := newClient()
+		analyzer := NewAnalyzer().WithDynamicClient(client).
+			WithAuthorizationClient(newAuthorizationClient("applications").AuthorizationV1())
+
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		texts := errorTexts(response)
+		assert.Contains(t, texts, "[ASA021/critical] Missing permission: analyzer cannot list applications.argoproj.io in all namespaces; live Application checks were skipped")
+		assert.Contains(t, texts, "[ASA015/warning] Generated Application guestbook-dev is not synced (status: OutOfSync)", "applicationStatus should still be analyzed")
+		assert.Contains(t, texts, "[ASA001/critical] ApplicationSet argocd/guestbook has error condition: broken", "other checks should still run")
+		assert.NotContains(t, texts, "[ASA016/warning] ApplicationSet argocd/guestbook has no generated applications")
+		assert.Empty(t, client.calls["applications"])
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		client := newClient()
+		analyzer := NewAnalyzer().WithDynamicClient(client).
+			WithAuthorizationClient(newAuthorizationClient().AuthorizationV1())
+
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		for _, text := range errorTexts(response) {
+			assert.NotContains(t, text, "Missing permission")
+		}
+		assert.Len(t, client.calls["applications"], 1)
+	})
+}
+
+func TestAnalyzer_Run_MultiCluster(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	newClient := func(names ...string) dynamic.Interface {
+		client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+		for _, name := range names {
+			_, err := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), newTestApplicationSet("argocd", name), metav1.CreateOptions{})
+			assert.NoError(t, err)
+		}
+		return client
+	}
+
+	cfg := config.Default()
+	cfg.KubeContexts = []string{"mgmt-eu", "mgmt-us"}
+	cfg.Checks.Enabled = []string{config.CheckConditions}
+
+	analyzer := NewAnalyzer().WithConfig(cfg).
+		WithClusterDynamicClient("mgmt-eu", newClient("guestbook")).
+		WithClusterDynamicClient("mgmt-us", newClient("guestbook", "payments"))
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.Equal(t, []string{
+		"[ASA001/critical] [mgmt-eu] ApplicationSet argocd/guestbook has error condition: broken",
+		"[ASA001/critical] [mgmt-us] ApplicationSet argocd/guestbook has error condition: broken",
+		"[ASA001/critical] [mgmt-us] ApplicationSet argocd/payments has error condition: broken",
+	}, texts)
+	assert.Contains(t, response.Result.Details, "Cluster mgmt-eu:\n  Found 1 ApplicationSet(s) in the cluster")
+	assert.Contains(t, response.Result.Details, "Cluster mgmt-us:\n  Found 2 ApplicationSet(s) in the cluster")
+	assert.Equal(t, float64(3), testutil.ToFloat64(metrics.ApplicationSetsScanned))
+	// The same namespace on different clusters is recorded as separate series
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.Findings.WithLabelValues("mgmt-eu", config.CheckConditions, "argocd")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.Findings.WithLabelValues("mgmt-us", config.CheckConditions, "argocd")))
+	assert.NoError(t, analyzer.Handler.Ping(context.TODO()))
+
+	// A cluster that cannot be reached does not hide the findings of the others
+	cfg = config.Default()
+	cfg.Kubeconfig = filepath.Join(t.TempDir(), "missing-kubeconfig")
+	cfg.KubeContexts = []string{"mgmt-eu", "unreachable"}
+	cfg.Checks.Enabled = []string{config.CheckConditions}
+
+	errorsBefore := testutil.ToFloat64(metrics.RunsTotal.WithLabelValues(metrics.RunResultError))
+	unreachableBefore := testutil.ToFloat64(metrics.ClusterRunsTotal.WithLabelValues("unreachable", metrics.RunResultError))
+	analyzer = NewAnalyzer().WithConfig(cfg).WithClusterDynamicClient("mgmt-eu", newClient("guestbook", "payments"))
+	response, err = analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	if assert.Len(t, response.Result.Error, 3) {
+		assert.Equal(t, "[ASA001/critical] [mgmt-eu] ApplicationSet argocd/guestbook has error condition: broken", response.Result.Error[0].Text)
+		assert.Equal(t, "[ASA001/critical] [mgmt-eu] ApplicationSet argocd/payments has error condition: broken", response.Result.Error[1].Text)
+		assert.True(t, strings.HasPrefix(response.Result.Error[2].Text, "[ASA022/critical] [unreachable] Could not connect to Kubernetes cluster"))
+	}
+	assert.Equal(t, errorsBefore+1, testutil.ToFloat64(metrics.RunsTotal.WithLabelValues(metrics.RunResultError)))
+	assert.Equal(t, unreachableBefore+1, testutil.ToFloat64(metrics.ClusterRunsTotal.WithLabelValues("unreachable", metrics.RunResultError)))
+	// The counters of the analyzed cluster are still published; those of other clusters are kept
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.ApplicationSetsScanned))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.Findings.WithLabelValues("mgmt-eu", config.CheckConditions, "argocd")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.Findings.WithLabelValues("mgmt-us", config.CheckConditions, "argocd")))
+	// The analyzer stays ready while at least one cluster is healthy
+	assert.NoError(t, analyzer.Handler.Ping(context.TODO()))
+
+	cfg.KubeContexts = []string{"unreachable"}
+	analyzer = NewAnalyzer().WithConfig(cfg)
+	assert.ErrorContains(t, analyzer.Handler.Ping(context.TODO()), "no cluster is healthy: cluster unreachable")
+}
+
+func TestAnalyzer_Run_Retries(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+
+	// newFailingClient fails the first failures ApplicationSet list calls with err
+	newFailingClient := func(failures int, err error) (*fake.FakeDynamicClient, *int) {
+		client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+		_, createErr := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), newTestApplicationSet("argocd", "guestbook"), metav1.CreateOptions{})
+		assert.NoError(t, createErr)
+
+		calls := 0
+		client.PrependReactor("list", "applicationsets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			calls++
+			if calls <= failures {
+				return true, nil, err
+			}
+			return false, nil, nil
+		})
+		return client, &calls
+	}
+
+	newConfig := func() *config.Config {
+		cfg := config.Default()
+		cfg.API.RetryBackoff = metav1.Duration{Duration: time.Millisecond}
+		return cfg
+	}
+
+	transientErrors := map[string]error{
+		"too many requests":   apierrors.NewTooManyRequests("slow down", 0),
+		"internal error":      apierrors.NewInternalError(errors.New("etcd leader changed")),
+		"service unavailable": apierrors.NewServiceUnavailable("apiserver restarting"),
+		"server timeout":      apierrors.NewServerTimeout(applicationSetGVR.GroupResource(), "list", 0),
+	}
+	for name, transientErr := range transientErrors {
+		t.Run(name, func(t *testing.T) {
+			client, calls := newFailingClient(2, transientErr)
+			retriesBefore := testutil.ToFloat64(metrics.APIRetriesTotal.WithLabelValues("argoproj.io", "v1alpha1", "applicationsets", "list"))
+
+			analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(newConfig())
+			response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+			assert.NoError(t, err)
+			assert.Contains(t, response.Result.Details, "Found 1 ApplicationSet(s)")
+			assert.Equal(t, 3, *calls)
+			assert.Equal(t, retriesBefore+2, testutil.ToFloat64(metrics.APIRetriesTotal.WithLabelValues("argoproj.io", "v1alpha1", "applicationsets", "list")))
+		})
+	}
+
+	t.Run("retries exhausted", func(t *testing.T) {
+		client, calls := newFailingClient(10, apierrors.NewServiceUnavailable("apiserver restarting"))
+		cfg := newConfig()
+		cfg.API.MaxRetries = 2
+
+		analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(cfg)
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		assert.Contains(t, response.Result.Details, "Failed to list ApplicationSets")
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("permanent error", func(t *testing.T) {
+		client, calls := newFailingClient(10, apierrors.NewForbidden(applicationSetGVR.GroupResource(), "", errors.New("denied")))
+
+		analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(newConfig())
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		assert.Contains(t, response.Result.Details, "Failed to list ApplicationSets")
+		assert.Equal(t, 1, *calls, "non-transient errors should not be retried")
+	})
+
+	t.Run("call budget", func(t *testing.T) {
+		client, calls := newFailingClient(10, apierrors.NewTooManyRequests("slow down", 0))
+		cfg := newConfig()
+		cfg.API.CallBudget = 3
+
+		analyzer := NewAnalyzer().WithDynamicClient(client).WithConfig(cfg)
+		response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		if assert.Len(t, response.Result.Error, 1) {
+			assert.Equal(t, "[ASA023/critical] Error listing ApplicationSets: API call budget of 3 calls per run exhausted", response.Result.Error[0].Text)
+		}
+		// One call lists the Applications, leaving two for ApplicationSets
+		assert.Equal(t, 2, *calls)
+
+		// The budget is per run
+		response, err = analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+		assert.NoError(t, err)
+		assert.Equal(t, 4, *calls)
+	})
+}
+
+func TestAnalyzer_Run_Suppressions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	fakeClient := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	// Each ApplicationSet reports ErrorOccurred (ASA001)
+	annotated := newTestApplicationSet("argocd", "annotated")
+	annotated.SetAnnotations(map[string]string{ignoreAnnotation: "asa001, ASA016"})
+	configured := newTestApplicationSet("team-a", "configured")
+	expired := newTestApplicationSet("team-b", "expired")
+	for _, appSet := range []*unstructured.Unstructured{annotated, configured, expired} {
+		_, err := fakeClient.Resource(applicationSetGVR).Namespace(appSet.GetNamespace()).Create(context.TODO(), appSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	// Generated Applications of the annotated ApplicationSet, one of them ignoring its sync status
+	for _, name := range []string{"ignored-app", "reported-app"} {
+		app := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "argoproj.io/v1alpha1",
+				"kind":       "Application",
+				"metadata": map[string]interface{}{
+					"name":      name,
+					"namespace": "argocd",
+					"labels":    map[string]interface{}{applicationSetNameLabel: "annotated"},
+				},
+				"status": map[string]interface{}{
+					"sync": map[string]interface{}{
+						"status": "OutOfSync",
+					},
+				},
+			},
+		}
+		if name == "ignored-app" {
+			app.SetAnnotations(map[string]string{ignoreAnnotation: "ASA018"})
+		}
+		_, err := fakeClient.Resource(applicationGVR).Namespace("argocd").Create(context.TODO(), app, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	cfg := config.Default()
+	cfg.Suppressions = []config.Suppression{
+		{Rules: []string{"ASA001", "ASA016"}, Namespace: "team-a"},
+		{Rules: []string{"*"}, Namespace: "team-b", Expires: "2020-01-01"},
+	}
+	analyzer := NewAnalyzer().WithConfig(cfg).WithDynamicClient(fakeClient)
+	response, err := analyzer.Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA018/warning] Application argocd/reported-app is not synced (status: OutOfSync)",
+		"[ASA001/critical] ApplicationSet team-b/expired has error condition: broken",
+		"[ASA016/warning] ApplicationSet team-b/expired has no generated applications",
+	}, texts)
+	assert.Contains(t, response.Result.Details, "Suppressed 4 finding(s) by ignore annotations and configured suppressions")
+}
+
+func TestAnalyzer_Run_ResultMode(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	broken := newTestApplicationSet("argocd", "broken")
+	healthy := newTestApplicationSet("argocd", "healthy")
+	healthy.Object["status"] = map[string]interface{}{
+		"applicationStatus": []interface{}{
+			map[string]interface{}{"application": "healthy-prod", "health": "Healthy", "sync": "Synced"},
+		},
+	}
+	for _, appSet := range []*unstructured.Unstructured{broken, healthy} {
+		_, err := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	cfg := config.Default()
+	cfg.ResultMode = config.ResultModePerApplicationSet
+	response, err := NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ApplicationSetList", response.Result.Kind)
+	assert.Empty(t, response.Result.ParentObject)
+	assert.Equal(t, strings.Join([]string{
+		"Found 2 ApplicationSet(s) in the cluster",
+		"=== ApplicationSet/argocd/broken (2 finding(s)) ===",
+		"  Condition: ErrorOccurred = True (broken)",
+		"  Findings:",
+		"    [ASA001/critical] ApplicationSet argocd/broken has error condition: broken",
+		"    [ASA016/warning] ApplicationSet argocd/broken has no generated applications",
+		"1 ApplicationSet(s) without findings",
+		"References:",
+		"  ASA001 status.conditions: https://argo-cd.readthedocs.io/en/stable/operator-manual/applicationset/applicationset-specification/",
+		"  ASA016 spec.generators: https://argo-cd.readthedocs.io/en/stable/operator-manual/applicationset/Generators/",
+	}, "\n"), response.Result.Details)
+	assert.Len(t, response.Result.Error, 2)
+
+	// A result covering a single ApplicationSet names it as the parent object
+	client = fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	_, err = client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), broken, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	cfg.ResultMode = config.ResultModeCombined
+	response, err = NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, "ApplicationSet", response.Result.Kind)
+	assert.Equal(t, "ApplicationSet/argocd/broken", response.Result.ParentObject)
+	assert.Contains(t, response.Result.Details, "ApplicationSet: argocd/broken")
+}
+
+func TestAnalyzer_Run_SensitiveMasking(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	const (
+		repoURL = "https://github.com/acme/payments.git"
+		server  = "https://payments.eks.example.com:443"
+	)
+	appSet := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "ApplicationSet",
+			"metadata": map[string]interface{}{
+				"name":      "payments",
+				"namespace": "team-payments",
+			},
+			"spec": map[string]interface{}{
+				"generators": []interface{}{
+					map[string]interface{}{
+						"git": map[string]interface{}{"repoURL": repoURL},
+					},
+					map[string]interface{}{
+						"clusterDecisionResource": map[string]interface{}{"configMapRef": "ocm-placement", "name": "payments-decisions"},
+					},
+					map[string]interface{}{
+						"clusterDecisionResource": map[string]interface{}{"configMapRef": "missing-placement", "name": "payments-decisions"},
+					},
+					map[string]interface{}{
+						"plugin": map[string]interface{}{"configMapRef": map[string]interface{}{"name": "billing-plugin"}},
+					},
+					map[string]interface{}{
+						"plugin": map[string]interface{}{"configMapRef": map[string]interface{}{"name": "ledger-plugin"}},
+					},
+				},
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"destination": map[string]interface{}{"server": server},
+					},
+				},
+			},
+			"status": map[string]interface{}{
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":    "ErrorOccurred",
+						"status":  "True",
+						"message": "failed to fetch " + repoURL + ": authentication required",
+					},
+				},
+				"applicationStatus": []interface{}{
+					map[string]interface{}{"application": "payments-eu", "health": "Degraded"},
+				},
+			},
+		},
+	}
+	app := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Application",
+			"metadata": map[string]interface{}{
+				"name":      "payments-prod",
+				"namespace": "team-payments",
+				"labels":    map[string]interface{}{applicationSetNameLabel: "payments"},
+			},
+			"spec": map[string]interface{}{
+				"destination": map[string]interface{}{"server": server},
+			},
+			"status": map[string]interface{}{
+				"health": map[string]interface{}{
+					"status":  "Degraded",
+					"message": "cluster " + server + " is unreachable",
+				},
+			},
+		},
+	}
+	_, err := client.Resource(applicationSetGVR).Namespace("team-payments").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	_, err = client.Resource(applicationGVR).Namespace("team-payments").Create(context.TODO(), app, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// Generator ConfigMaps name secrets, hosts and Services that are not in the ApplicationSet spec
+	for name, data := range map[string]map[string]interface{}{
+		"ocm-placement": {
+			"apiVersion": "cluster.open-cluster-management.io/v1beta1", "kind": "PlacementDecision",
+			"statusListKey": "decisions", "matchKey": "clusterName",
+		},
+		"billing-plugin": {"token": "$billing-token:token", "baseUrl": "http://billing-generator.plugins.svc:4355"},
+		"ledger-plugin":  {"token": "plain-token", "baseUrl": "ledger-generator:4355"},
+	} {
+		configMap := &unstructured.Unstructured{Object: map[string]interface{}{"data": data}}
+		configMap.SetAPIVersion("v1")
+		configMap.SetKind("ConfigMap")
+		configMap.SetName(name)
+		_, err = client.Resource(configMapGVR).Namespace("argocd").Create(context.TODO(), configMap, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	response, err := NewAnalyzer().WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Result.Error)
+
+	raw := []string{repoURL, server, "team-payments", "payments", "payments-eu", "payments-prod", "github.com", "example.com",
+		"argocd", "ocm-placement", "missing-placement", "billing-plugin", "ledger-plugin", "billing-token", "billing-generator", "plugins", "ledger-generator"}
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	for _, rule := range []string{"ASA034", "ASA041", "ASA043", "ASA045"} {
+		assert.Contains(t, strings.Join(texts, "\n"), "["+rule+"/", "generator findings should be covered")
+	}
+	for _, e := range response.Result.Error {
+		assert.NotEmpty(t, e.Sensitive, "finding %q should carry sensitive data", e.Text)
+
+		// Anonymize the way k8sgpt does before sending findings to an AI backend
+		masked := e.Text
+		for _, s := range e.Sensitive {
+			masked = strings.ReplaceAll(masked, s.Unmasked, s.Masked)
+		}
+		for _, value := range raw {
+			assert.NotContains(t, masked, value, "masked finding %q leaks a raw value", masked)
+		}
+
+		// Masking is reversible, so explanations can be mapped back to the original values
+		unmasked := masked
+		for _, s := range e.Sensitive {
+			unmasked = strings.ReplaceAll(unmasked, s.Masked, s.Unmasked)
+		}
+		assert.Equal(t, e.Text, unmasked)
+	}
+}
+
+func TestAnalyzer_Run_MatrixGenerator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	list := func(elements ...map[string]interface{}) map[string]interface{} {
+		items := make([]interface{}, len(elements))
+		for i, element := range elements {
+			items[i] = element
+		}
+		return map[string]interface{}{"list": map[string]interface{}{"elements": items}}
+	}
+	matrix := func(children ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"matrix": map[string]interface{}{"generators": children}}
+	}
+	git := func(repoURL, prefix string) map[string]interface{} {
+		spec := map[string]interface{}{
+			"repoURL":     repoURL,
+			"directories": []interface{}{map[string]interface{}{"path": "apps/*"}},
+		}
+		if prefix != "" {
+			spec["pathParamPrefix"] = prefix
+		}
+		return map[string]interface{}{"git": spec}
+	}
+	env := map[string]interface{}{"env": "prod"}
+
+	appSet := newTestApplicationSet("argocd", "matrix")
+	delete(appSet.Object, "status")
+	appSet.Object["spec"] = map[string]interface{}{
+		"generators": []interface{}{
+			// Only one child
+			matrix(list(env)),
+			// Two Git generators without pathParamPrefix, one of them invalid
+			matrix(git("", ""), git("https://github.com/acme/config.git", "")),
+			// Matrix nested three levels deep
+			matrix(matrix(matrix(list(env), list(map[string]interface{}{"region": "eu"})),
+				list(map[string]interface{}{"team": "a"})), list(map[string]interface{}{"tier": "web"})),
+			// List element and cluster parameters named server
+			matrix(list(map[string]interface{}{"server": "https://kubernetes.default.svc", "env": "prod"}),
+				map[string]interface{}{"clusters": map[string]interface{}{"selector": map[string]interface{}{}}}),
+			// Valid: one level of nesting and prefixed Git parameters
+			matrix(matrix(git("https://github.com/acme/apps.git", "apps"), git("https://github.com/acme/config.git", "config")), list(env)),
+		},
+	}
+	_, err := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.Checks.Enabled = []string{config.CheckGenerators}
+	response, err := NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA025/critical] ApplicationSet argocd/matrix Matrix generator at index 0 has 1 child generators; exactly 2 are required",
+		"[ASA009/critical] ApplicationSet argocd/matrix Git generator at index 1, matrix child 0 has empty repoURL",
+		"[ASA027/warning] ApplicationSet argocd/matrix Matrix generator at index 1 has children that both produce parameters path, path.basename, path.basenameNormalized; the second child's values override the first",
+		"[ASA026/critical] ApplicationSet argocd/matrix Matrix generator at index 2, matrix child 0, matrix child 0 is nested 3 levels deep; Argo CD supports at most 2",
+		"[ASA027/warning] ApplicationSet argocd/matrix Matrix generator at index 3 has children that both produce parameters server; the second child's values override the first",
+	}, texts)
+}
+
+func TestAnalyzer_Run_MergeGenerator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	list := func(elements ...map[string]interface{}) map[string]interface{} {
+		items := make([]interface{}, len(elements))
+		for i, element := range elements {
+			items[i] = element
+		}
+		return map[string]interface{}{"list": map[string]interface{}{"elements": items}}
+	}
+	merge := func(mergeKeys []interface{}, children ...interface{}) map[string]interface{} {
+		return map[string]interface{}{"merge": map[string]interface{}{"mergeKeys": mergeKeys, "generators": children}}
+	}
+	clusters := map[string]interface{}{"clusters": map[string]interface{}{
+		"selector": map[string]interface{}{},
+		"values":   map[string]interface{}{"env": "prod"},
+	}}
+	envKey := []interface{}{"env"}
+
+	appSet := newTestApplicationSet("argocd", "merge")
+	delete(appSet.Object, "status")
+	appSet.Object["spec"] = map[string]interface{}{
+		"generators": []interface{}{
+			// No merge keys and an empty base
+			merge(nil, map[string]interface{}{}, list(map[string]interface{}{"env": "prod"})),
+			// The secondary List generator lacks the merge key
+			merge(envKey, list(map[string]interface{}{"env": "prod"}), list(map[string]interface{}{"replicas": "3"})),
+			// The secondary List generator never matches the base
+			merge(envKey, list(map[string]interface{}{"env": "prod"}), list(map[string]interface{}{"env": "staging", "replicas": "3"})),
+			// Cluster parameters are matched by name; labels are unknown until runtime
+			merge([]interface{}{"server"}, clusters, list(map[string]interface{}{"server": "https://kubernetes.default.svc"})),
+			merge([]interface{}{"metadata.labels.env"}, clusters, list(map[string]interface{}{"metadata.labels.env": "prod"})),
+			// Cluster values match the secondary List generator
+			merge([]interface{}{"values.env"}, clusters, list(map[string]interface{}{"values.env": "prod"})),
+			// Cluster values never match
+			merge([]interface{}{"values.env"}, clusters, list(map[string]interface{}{"values.env": "dev"})),
+			// A finding about the base that leaves its parameters known does not skip the merge checks
+			merge(envKey, list(), list(map[string]interface{}{"env": "staging", "replicas": "3"})),
+		},
+	}
+	_, err := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.Checks.Enabled = []string{config.CheckGenerators}
+	response, err := NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA028/critical] ApplicationSet argocd/merge Merge generator at index 0 has no mergeKeys",
+		"[ASA029/critical] ApplicationSet argocd/merge Merge generator at index 0 has no base generator",
+		"[ASA030/critical] ApplicationSet argocd/merge Merge generator at index 1 has child 1 that does not produce merge keys env",
+		"[ASA031/warning] ApplicationSet argocd/merge Merge generator at index 2 has child 1 whose merge key values never match the base generator, so it overrides nothing",
+		"[ASA031/warning] ApplicationSet argocd/merge Merge generator at index 6 has child 1 whose merge key values never match the base generator, so it overrides nothing",
+		"[ASA011/warning] ApplicationSet argocd/merge List generator at index 7, merge child 0 has empty elements array",
+		"[ASA031/warning] ApplicationSet argocd/merge Merge generator at index 7 has child 1 whose merge key values never match the base generator, so it overrides nothing",
+	}, texts)
+}
+
+func TestAnalyzer_Run_SCMProviderGenerator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      "github-token",
+				"namespace": "argocd",
+			},
+			"data": map[string]interface{}{"token": "c2VjcmV0"},
+		},
+	}
+	_, err := client.Resource(secretGVR).Namespace("argocd").Create(context.TODO(), secret, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	scm := func(spec map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"scmProvider": spec}
+	}
+	tokenRef := func(name, key string) map[string]interface{} {
+		return map[string]interface{}{"secretName": name, "key": key}
+	}
+
+	appSet := newTestApplicationSet("argocd", "scm")
+	delete(appSet.Object, "status")
+	appSet.Object["spec"] = map[string]interface{}{
+		"generators": []interface{}{
+			scm(map[string]interface{}{}),
+			// Missing organization and a key the secret does not have
+			scm(map[string]interface{}{
+				"github": map[string]interface{}{"tokenRef": tokenRef("github-token", "password")},
+			}),
+			// Missing secret and invalid filters
+			scm(map[string]interface{}{
+				"gitlab": map[string]interface{}{"group": "acme", "tokenRef": tokenRef("gitlab-token", "token")},
+				"filters": []interface{}{
+					map[string]interface{}{"repositoryMatch": "^(app", "pathsExist": []interface{}{""}},
+				},
+			}),
+			// Cloning over https from a server whose API is plain http
+			scm(map[string]interface{}{
+				"bitbucketServer": map[string]interface{}{"project": "PLAT", "api": "http://bitbucket.acme.internal"},
+				"cloneProtocol":   "https",
+			}),
+			// FIPS endpoints are only available for AWS CodeCommit
+			scm(map[string]interface{}{
+				"gitea":         map[string]interface{}{"owner": "acme", "api": "https://gitea.acme.internal"},
+				"cloneProtocol": "https-fips",
+			}),
+			scm(map[string]interface{}{
+				"github": map[string]interface{}{"organization": "acme"},
+				"gitlab": map[string]interface{}{"group": "acme"},
+			}),
+			// Valid
+			scm(map[string]interface{}{
+				"github":        map[string]interface{}{"organization": "acme", "tokenRef": tokenRef("github-token", "token")},
+				"cloneProtocol": "ssh",
+				"filters": []interface{}{
+					map[string]interface{}{"repositoryMatch": "^app-", "branchMatch": "^main$", "pathsExist": []interface{}{"kustomization.yaml"}},
+				},
+			}),
+		},
+	}
+	_, err = client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// GitHub App repo-creds live in the Argo CD namespace, whatever the ApplicationSet's namespace
+	appCreds := secret.DeepCopy()
+	appCreds.SetName("github-app")
+	_, err = client.Resource(secretGVR).Namespace("argocd").Create(context.TODO(), appCreds, metav1.CreateOptions{})
+	assert.NoError(t, err)
+	teamAppSet := newTestApplicationSet("team-a", "scm")
+	delete(teamAppSet.Object, "status")
+	teamAppSet.Object["spec"] = map[string]interface{}{
+		"generators": []interface{}{
+			scm(map[string]interface{}{
+				"github": map[string]interface{}{"organization": "acme", "appSecretName": "github-app"},
+			}),
+			scm(map[string]interface{}{
+				"github": map[string]interface{}{"organization": "acme", "appSecretName": "missing-app"},
+			}),
+		},
+	}
+	_, err = client.Resource(applicationSetGVR).Namespace("team-a").Create(context.TODO(), teamAppSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.Checks.Enabled = []string{config.CheckGenerators}
+	response, err := NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA032/critical] ApplicationSet argocd/scm SCM Provider generator at index 0 has no provider configured",
+		"[ASA033/critical] ApplicationSet argocd/scm SCM Provider generator at index 1 has no github.organization",
+		`[ASA035/critical] ApplicationSet argocd/scm SCM Provider generator at index 1: github.tokenRef references key "password", which secret argocd/github-token does not have`,
+		"[ASA034/critical] ApplicationSet argocd/scm SCM Provider generator at index 2: gitlab.tokenRef references secret argocd/gitlab-token, which does not exist",
+		"[ASA036/critical] ApplicationSet argocd/scm SCM Provider generator at index 2 filter 0 has invalid repositoryMatch \"^(app\": error parsing regexp: missing closing ): `^(app`",
+		"[ASA036/critical] ApplicationSet argocd/scm SCM Provider generator at index 2 filter 0 has an empty path in pathsExist",
+		"[ASA037/warning] ApplicationSet argocd/scm SCM Provider generator at index 3 clones over https but its API http://bitbucket.acme.internal is served over plain http",
+		`[ASA037/warning] ApplicationSet argocd/scm SCM Provider generator at index 4 has cloneProtocol "https-fips", which gitea does not support (supported: ssh, https)`,
+		"[ASA032/critical] ApplicationSet argocd/scm SCM Provider generator at index 5 configures several providers (github, gitlab); exactly one is required",
+		"[ASA034/critical] ApplicationSet team-a/scm SCM Provider generator at index 1: github.appSecretName references secret argocd/missing-app, which does not exist",
+	}, texts)
+}
+
+func TestAnalyzer_Run_PullRequestGenerator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	secret := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"name":      "github-token",
+				"namespace": "argocd",
+			},
+			"data": map[string]interface{}{"token": "c2VjcmV0"},
+		},
+	}
+	_, err := client.Resource(secretGVR).Namespace("argocd").Create(context.TODO(), secret, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	pr := func(spec map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"pullRequest": spec}
+	}
+	tokenRef := func(name, key string) map[string]interface{} {
+		return map[string]interface{}{"secretName": name, "key": key}
+	}
+
+	appSet := newTestApplicationSet("argocd", "previews")
+	appSet.Object["spec"] = map[string]interface{}{
+		"generators": []interface{}{
+			pr(map[string]interface{}{}),
+			// Missing repo and a key the secret does not have
+			pr(map[string]interface{}{
+				"github": map[string]interface{}{"owner": "acme", "tokenRef": tokenRef("github-token", "password")},
+			}),
+			// Missing secret, empty label and invalid filters
+			pr(map[string]interface{}{
+				"gitea": map[string]interface{}{
+					"owner": "acme", "repo": "app", "api": "https://gitea.acme.internal",
+					"tokenRef": tokenRef("gitea-token", "token"),
+					"labels":   []interface{}{"preview", ""},
+				},
+				"filters": []interface{}{
+					map[string]interface{}{"branchMatch": "feature-(", "targetBranchMatch": "^main$"},
+				},
+			}),
+			pr(map[string]interface{}{
+				"github": map[string]interface{}{"owner": "acme", "repo": "app"},
+				"gitlab": map[string]interface{}{"project": "acme/app"},
+			}),
+			// Valid, nested in a matrix with a faster requeue
+			map[string]interface{}{
+				"matrix": map[string]interface{}{
+					"generators": []interface{}{
+						map[string]interface{}{"list": map[string]interface{}{
+							"elements": []interface{}{map[string]interface{}{"env": "preview"}},
+						}},
+						pr(map[string]interface{}{
+							"github":              map[string]interface{}{"owner": "acme", "repo": "app", "tokenRef": tokenRef("github-token", "token")},
+							"requeueAfterSeconds": int64(600),
+						}),
+					},
+				},
+			},
+		},
+		"syncPolicy": map[string]interface{}{"applicationsSync": "create-update"},
+	}
+	resourcesUpToDate := func(since time.Duration) []interface{} {
+		return []interface{}{map[string]interface{}{
+			"type":               "ResourcesUpToDate",
+			"status":             "True",
+			"lastTransitionTime": time.Now().Add(-since).UTC().Format(time.RFC3339),
+		}}
+	}
+	appSet.Object["status"] = map[string]interface{}{
+		"resources": []interface{}{
+			map[string]interface{}{"kind": "Application", "name": "app-pr-12", "namespace": "argocd"},
+		},
+		"conditions": resourcesUpToDate(2 * time.Hour),
+	}
+	_, err = client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// Pull requests closed since the last reconcile, or without a ResourcesUpToDate condition
+	// telling when that was, are not reported however long their Applications have existed
+	for name, conditions := range map[string][]interface{}{
+		"recent-previews":  resourcesUpToDate(5 * time.Minute),
+		"unknown-previews": nil,
+	} {
+		other := newTestApplicationSet("argocd", name)
+		other.Object["spec"] = map[string]interface{}{
+			"generators": []interface{}{pr(map[string]interface{}{
+				"github": map[string]interface{}{"owner": "acme", "repo": "app"},
+			})},
+		}
+		other.Object["status"] = map[string]interface{}{"resources": []interface{}{}, "conditions": conditions}
+		_, err = client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), other, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	preview := func(appSetName, name string, age time.Duration) *unstructured.Unstructured {
+		app := &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "argoproj.io/v1alpha1",
+				"kind":       "Application",
+				"metadata": map[string]interface{}{
+					"name":      name,
+					"namespace": "argocd",
+					"labels": map[string]interface{}{
+						"argocd.argoproj.io/application-set-name": appSetName,
+					},
+				},
+				"status": map[string]interface{}{
+					"health": map[string]interface{}{"status": "Healthy"},
+					"sync":   map[string]interface{}{"status": "Synced"},
+				},
+			},
+		}
+		app.SetCreationTimestamp(metav1.NewTime(time.Now().Add(-age)))
+		return app
+	}
+	for _, app := range []*unstructured.Unstructured{
+		// Still generated
+		preview("previews", "app-pr-12", 72*time.Hour),
+		// Recently closed, not yet due for pruning
+		preview("previews", "app-pr-13", 10*time.Minute),
+		// Closed long before the last reconcile
+		preview("previews", "app-pr-7", 30*24*time.Hour),
+		// Long-lived, but their pull requests may have closed just now
+		preview("recent-previews", "app-pr-3", 21*24*time.Hour),
+		preview("unknown-previews", "app-pr-4", 21*24*time.Hour),
+	} {
+		_, err = client.Resource(applicationGVR).Namespace("argocd").Create(context.TODO(), app, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	cfg := config.Default()
+	cfg.Checks.Enabled = []string{config.CheckGenerators, config.CheckApplications}
+	response, err := NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA038/critical] ApplicationSet argocd/previews Pull Request generator at index 0 has no provider configured",
+		"[ASA039/critical] ApplicationSet argocd/previews Pull Request generator at index 1 has no github.repo",
+		`[ASA035/critical] ApplicationSet argocd/previews Pull Request generator at index 1: github.tokenRef references key "password", which secret argocd/github-token does not have`,
+		"[ASA034/critical] ApplicationSet argocd/previews Pull Request generator at index 2: gitea.tokenRef references secret argocd/gitea-token, which does not exist",
+		"[ASA036/critical] ApplicationSet argocd/previews Pull Request generator at index 2 has an empty label in gitea.labels",
+		"[ASA036/critical] ApplicationSet argocd/previews Pull Request generator at index 2 filter 0 has invalid branchMatch \"feature-(\": error parsing regexp: missing closing ): `feature-(`",
+		"[ASA038/critical] ApplicationSet argocd/previews Pull Request generator at index 3 configures several providers (github, gitlab); exactly one is required",
+		"[ASA040/warning] Application argocd/app-pr-7 is no longer generated by the Pull Request generator of ApplicationSet argocd/previews but still exists 2h0m0s after its resources were last reconciled (pull requests are polled every 10m0s); applicationsSync create-update prevents its deletion",
+	}, texts)
+}
+
+func TestAnalyzer_Run_ClusterDecisionResourceAndPluginGenerators(t *testing.T) {
+	placementDecisionGVR := schema.GroupVersionResource{Group: "cluster.open-cluster-management.io", Version: "v1beta1", Resource: "placementdecisions"}
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR:    "ApplicationSetList",
+		applicationGVR:       "ApplicationList",
+		placementDecisionGVR: "PlacementDecisionList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+
+	object := func(apiVersion, kind, namespace, name string, fields map[string]interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: fields}
+		obj.SetAPIVersion(apiVersion)
+		obj.SetKind(kind)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		return obj
+	}
+	for _, create := range []struct {
+		gvr schema.GroupVersionResource
+		obj *unstructured.Unstructured
+	}{
+		{configMapGVR, object("v1", "ConfigMap", "argocd", "ocm-placement", map[string]interface{}{
+			"data": map[string]interface{}{
+				"apiVersion":    "cluster.open-cluster-management.io/v1beta1",
+				"kind":          "PlacementDecision",
+				"statusListKey": "decisions",
+				"matchKey":      "clusterName",
+			},
+		})},
+		{configMapGVR, object("v1", "ConfigMap", "argocd", "incomplete", map[string]interface{}{
+			"data": map[string]interface{}{"apiVersion": "cluster.open-cluster-management.io/v1beta1"},
+		})},
+		{configMapGVR, object("v1", "ConfigMap", "argocd", "plugin-missing-service", map[string]interface{}{
+			"data": map[string]interface{}{"token": "$plugin.matrix.token", "baseUrl": "http://matrix-plugin.argocd.svc.cluster.local:4355"},
+		})},
+		{configMapGVR, object("v1", "ConfigMap", "argocd", "plugin-bad-url", map[string]interface{}{
+			"data": map[string]interface{}{"token": "$plugin-secret:token", "baseUrl": "matrix-plugin:4355"},
+		})},
+		{configMapGVR, object("v1", "ConfigMap", "argocd", "plugin-valid", map[string]interface{}{
+			"data": map[string]interface{}{"token": "$plugin.valid.token", "baseUrl": "http://valid-plugin:4355"},
+		})},
+		{secretGVR, object("v1", "Secret", "argocd", "argocd-secret", map[string]interface{}{
+			"data": map[string]interface{}{"plugin.valid.token": "c2VjcmV0"},
+		})},
+		{serviceGVR, object("v1", "Service", "argocd", "valid-plugin", map[string]interface{}{})},
+		{placementDecisionGVR, object("cluster.open-cluster-management.io/v1beta1", "PlacementDecision", "argocd", "placement-ok", map[string]interface{}{
+			"status": map[string]interface{}{
+				"decisions": []interface{}{map[string]interface{}{"clusterName": "cluster-1"}},
+			},
+		})},
+		{placementDecisionGVR, object("cluster.open-cluster-management.io/v1beta1", "PlacementDecision", "argocd", "placement-no-status", map[string]interface{}{})},
+		{placementDecisionGVR, object("cluster.open-cluster-management.io/v1beta1", "PlacementDecision", "argocd", "placement-wrong-key", map[string]interface{}{
+			"status": map[string]interface{}{
+				"decisions": []interface{}{map[string]interface{}{"cluster": "cluster-1"}},
+			},
+		})},
+	} {
+		_, err := client.Resource(create.gvr).Namespace("argocd").Create(context.TODO(), create.obj, metav1.CreateOptions{})
+		assert.NoError(t, err)
+	}
+
+	cdr := func(spec map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"clusterDecisionResource": spec}
+	}
+	plugin := func(configMap string) map[string]interface{} {
+		return map[string]interface{}{"plugin": map[string]interface{}{
+			"configMapRef": map[string]interface{}{"name": configMap},
+		}}
+	}
+
+	appSet := newTestApplicationSet("argocd", "decisions")
+	delete(appSet.Object, "status")
+	appSet.Object["spec"] = map[string]interface{}{
+		"generators": []interface{}{
+			cdr(map[string]interface{}{"name": "placement-ok"}),
+			cdr(map[string]interface{}{"configMapRef": "missing", "name": "placement-ok"}),
+			cdr(map[string]interface{}{"configMapRef": "incomplete"}),
+			cdr(map[string]interface{}{"configMapRef": "ocm-placement", "name": "placement-gone"}),
+			cdr(map[string]interface{}{"configMapRef": "ocm-placement", "name": "placement-no-status"}),
+			cdr(map[string]interface{}{"configMapRef": "ocm-placement", "name": "placement-wrong-key"}),
+			cdr(map[string]interface{}{"configMapRef": "ocm-placement", "labelSelector": map[string]interface{}{
+				"matchLabels": map[string]interface{}{"placement": "none"},
+			}}),
+			plugin("plugin-missing-service"),
+			plugin("plugin-bad-url"),
+			// Valid
+			cdr(map[string]interface{}{"configMapRef": "ocm-placement", "name": "placement-ok"}),
+			plugin("plugin-valid"),
+		},
+	}
+	_, err := client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.Checks.Enabled = []string{config.CheckGenerators}
+	response, err := NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA041/critical] ApplicationSet argocd/decisions ClusterDecisionResource generator at index 0 has no configMapRef",
+		"[ASA041/critical] ApplicationSet argocd/decisions ClusterDecisionResource generator at index 1 references ConfigMap argocd/missing, which does not exist",
+		"[ASA042/critical] ApplicationSet argocd/decisions ClusterDecisionResource generator at index 2: ConfigMap argocd/incomplete has no kind, statusListKey, matchKey",
+		"[ASA043/critical] ApplicationSet argocd/decisions ClusterDecisionResource generator at index 2 has neither name nor labelSelector",
+		"[ASA043/critical] ApplicationSet argocd/decisions ClusterDecisionResource generator at index 3 references PlacementDecision argocd/placement-gone, which does not exist",
+		"[ASA044/warning] ApplicationSet argocd/decisions ClusterDecisionResource generator at index 4: PlacementDecision argocd/placement-no-status has no status.decisions list",
+		"[ASA044/warning] ApplicationSet argocd/decisions ClusterDecisionResource generator at index 5: PlacementDecision argocd/placement-wrong-key has no clusterName in any status.decisions entry",
+		"[ASA043/critical] ApplicationSet argocd/decisions ClusterDecisionResource generator at index 6: no PlacementDecision in namespace argocd matches labelSelector placement=none",
+		`[ASA035/critical] ApplicationSet argocd/decisions Plugin generator at index 7: token of ConfigMap argocd/plugin-missing-service references key "plugin.matrix.token", which secret argocd/argocd-secret does not have`,
+		"[ASA045/critical] ApplicationSet argocd/decisions Plugin generator at index 7: baseUrl http://matrix-plugin.argocd.svc.cluster.local:4355 of ConfigMap argocd/plugin-missing-service points to Service argocd/matrix-plugin, which does not exist",
+		"[ASA034/critical] ApplicationSet argocd/decisions Plugin generator at index 8: token of ConfigMap argocd/plugin-bad-url references secret argocd/plugin-secret, which does not exist",
+		`[ASA045/critical] ApplicationSet argocd/decisions Plugin generator at index 8: ConfigMap argocd/plugin-bad-url has baseUrl "matrix-plugin:4355", which is not an http(s) URL`,
+	}, texts)
+}
+
+func TestAnalyzer_Run_GeneratorReferencesForbidden(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	client.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.GetAction).GetName()
+		return true, nil, apierrors.NewForbidden(secretGVR.GroupResource(), name, errors.New("denied"))
+	})
+	client.PrependReactor("get", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if name := action.(k8stesting.GetAction).GetName(); name == "restricted" {
+			return true, nil, apierrors.NewForbidden(configMapGVR.GroupResource(), name, errors.New("denied"))
+		}
+		return false, nil, nil
+	})
+	client.PrependReactor("get", "services", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		name := action.(k8stesting.GetAction).GetName()
+		return true, nil, apierrors.NewForbidden(serviceGVR.GroupResource(), name, errors.New("denied"))
+	})
+
+	plugin := &unstructured.Unstructured{Object: map[string]interface{}{
+		"data": map[string]interface{}{"token": "plain-token", "baseUrl": "http://matrix-plugin:4355"},
+	}}
+	plugin.SetAPIVersion("v1")
+	plugin.SetKind("ConfigMap")
+	plugin.SetName("plugin")
+	_, err := client.Resource(configMapGVR).Namespace("argocd").Create(context.TODO(), plugin, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	appSet := newTestApplicationSet("argocd", "scm")
+	delete(appSet.Object, "status")
+	appSet.Object["spec"] = map[string]interface{}{
+		"generators": []interface{}{
+			map[string]interface{}{"scmProvider": map[string]interface{}{
+				"github": map[string]interface{}{
+					"organization": "acme",
+					"tokenRef":     map[string]interface{}{"secretName": "github-token", "key": "token"},
+				},
+			}},
+			map[string]interface{}{"clusterDecisionResource": map[string]interface{}{
+				"configMapRef": "restricted", "name": "placement",
+			}},
+			map[string]interface{}{"plugin": map[string]interface{}{
+				"configMapRef": map[string]interface{}{"name": "plugin"},
+			}},
+		},
+	}
+	_, err = client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	// The preflight denies reading secrets, as the API server does
+	clientset := kubefake.NewSimpleClientset()
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		review.Status.Allowed = review.Spec.ResourceAttributes.Resource != "secrets"
+		return true, review, nil
+	})
+
+	cfg := config.Default()
+	cfg.Checks.Enabled = []string{config.CheckGenerators}
+	response, err := NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).
+		WithAuthorizationClient(clientset.AuthorizationV1()).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA021/critical] Missing permission: analyzer cannot get secrets in all namespaces; generator checks needing this access were skipped",
+		"[ASA021/critical] Missing permission: analyzer cannot get secrets in namespace argocd; the check of ApplicationSet argocd/scm SCM Provider generator at index 0 was skipped",
+		"[ASA021/critical] Missing permission: analyzer cannot get configmaps in namespace argocd; the check of ApplicationSet argocd/scm ClusterDecisionResource generator at index 1 was skipped",
+		"[ASA021/critical] Missing permission: analyzer cannot get services in namespace argocd; the check of ApplicationSet argocd/scm Plugin generator at index 2 was skipped",
+	}, texts)
+}
+
+func TestAnalyzer_Run_GeneratorReferencesUnverified(t *testing.T) {
+	scheme := runtime.NewScheme()
+	listKinds := map[schema.GroupVersionResource]string{
+		applicationSetGVR: "ApplicationSetList",
+		applicationGVR:    "ApplicationList",
+	}
+	client := fake.NewSimpleDynamicClientWithCustomListKinds(scheme, listKinds)
+	serverError := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if name := action.(k8stesting.GetAction).GetName(); name != "plugin" {
+			return true, nil, apierrors.NewInternalError(errors.New("etcdserver: request timed out"))
+		}
+		return false, nil, nil
+	}
+	for _, resource := range []string{"secrets", "configmaps", "services"} {
+		client.PrependReactor("get", resource, serverError)
+	}
+
+	plugin := &unstructured.Unstructured{Object: map[string]interface{}{
+		"data": map[string]interface{}{"token": "plain-token", "baseUrl": "http://matrix-plugin:4355"},
+	}}
+	plugin.SetAPIVersion("v1")
+	plugin.SetKind("ConfigMap")
+	plugin.SetName("plugin")
+	_, err := client.Resource(configMapGVR).Namespace("argocd").Create(context.TODO(), plugin, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	appSet := newTestApplicationSet("argocd", "scm")
+	delete(appSet.Object, "status")
+	appSet.Object["spec"] = map[string]interface{}{
+		"generators": []interface{}{
+			map[string]interface{}{"scmProvider": map[string]interface{}{
+				"github": map[string]interface{}{
+					"organization": "acme",
+					"tokenRef":     map[string]interface{}{"secretName": "github-token", "key": "token"},
+				},
+			}},
+			map[string]interface{}{"clusterDecisionResource": map[string]interface{}{
+				"configMapRef": "placements", "name": "placement",
+			}},
+			map[string]interface{}{"plugin": map[string]interface{}{
+				"configMapRef": map[string]interface{}{"name": "plugin"},
+			}},
+		},
+	}
+	_, err = client.Resource(applicationSetGVR).Namespace("argocd").Create(context.TODO(), appSet, metav1.CreateOptions{})
+	assert.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.Checks.Enabled = []string{config.CheckGenerators}
+	cfg.API.MaxRetries = 0
+	incompleteBefore := testutil.ToFloat64(metrics.RunsTotal.WithLabelValues(metrics.RunResultIncomplete))
+	response, err := NewAnalyzer().WithConfig(cfg).WithDynamicClient(client).Handler.Run(context.TODO(), &v1.RunRequest{})
+	assert.NoError(t, err)
+
+	// Server errors are reported instead of being mistaken for clean results
+	var texts []string
+	for _, e := range response.Result.Error {
+		texts = append(texts, e.Text)
+	}
+	assert.ElementsMatch(t, []string{
+		"[ASA048/warning] Could not verify secret argocd/github-token referenced by ApplicationSet argocd/scm SCM Provider generator at index 0: Internal error occurred: etcdserver: request timed out; the check was skipped",
+		"[ASA048/warning] Could not verify ConfigMap argocd/placements referenced by ApplicationSet argocd/scm ClusterDecisionResource generator at index 1: Internal error occurred: etcdserver: request timed out; the check was skipped",
+		"[ASA048/warning] Could not verify Service argocd/matrix-plugin referenced by ApplicationSet argocd/scm Plugin generator at index 2: Internal error occurred: etcdserver: request timed out; the check was skipped",
+	}, texts)
+	assert.Equal(t, incompleteBefore+1, testutil.ToFloat64(metrics.RunsTotal.WithLabelValues(metrics.RunResultIncomplete)))
+}
+
+func TestPluginService(t *testing.T) {
+	tests := []struct {
+		host      string
+		namespace string
+		name      string
+		found     bool
+	}{
+		{host: "matrix-plugin", namespace: "argocd", name: "matrix-plugin", found: true},
+		{host: "matrix-plugin.plugins", namespace: "plugins", name: "matrix-plugin", found: true},
+		{host: "matrix-plugin.plugins.svc", namespace: "plugins", name: "matrix-plugin", found: true},
+		{host: "matrix-plugin.plugins.svc.cluster.local", namespace: "plugins", name: "matrix-plugin", found: true},
+		{host: "localhost"},
+		{host: "10.0.0.12"},
+		{host: "plugin.example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			namespace, name, found := pluginService(tt.host, "argocd")
+			assert.Equal(t, tt.found, found)
+			assert.Equal(t, tt.namespace, namespace)
+			assert.Equal(t, tt.name, name)
+		})
+	}
+}
@@ -4,50 +4,99 @@ import (
 	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
 	"context"
 	"fmt"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+
+	"github.com/ranakan19/custom-analyzer/pkg/config"
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
-// analyzeApplication analyzes individual application health and sync status
-func (a *Handler) analyzeApplication(app *unstructured.Unstructured) []*v1.ErrorDetail {
+// reportedStatus is the health and sync status the ApplicationSet reports for a generated
+// Application in status.applicationStatus
+type reportedStatus struct {
+	health  string
+	sync    string
+	message string
+}
+
+// analyzeApplication analyzes individual application health and sync status. If the
+// ApplicationSet status also reports the Application, each finding cites both sources
+// and a disagreement between them is flagged as a stale applicationStatus.
+func (a *Handler) analyzeApplication(app *unstructured.Unstructured, reported *reportedStatus) []*v1.ErrorDetail {
 	var errors []*v1.ErrorDetail
+	if reported == nil {
+		reported = &reportedStatus{}
+	}
 
-	// Check health status
-	health, found, err := unstructured.NestedFieldNoCopy(app.Object, "status", "health", "status")
-	if err == nil && found {
-		if healthStr, ok := health.(string); ok && healthStr != "Healthy" {
-			healthMessage, _, _ := unstructured.NestedString(app.Object, "status", "health", "message")
-			errors = append(errors, &v1.ErrorDetail{
-				Text: fmt.Sprintf("Application %s/%s is not healthy (status: %s): %s",
-					app.GetNamespace(), app.GetName(), healthStr, healthMessage),
-			})
+	// Check health status, falling back to the ApplicationSet status when the live
+	// Application does not report one yet
+	healthStr, found, err := unstructured.NestedString(app.Object, "status", "health", "status")
+	switch {
+	case err != nil || !found || healthStr == "":
+		if reported.health != "" && reported.health != "Healthy" {
+			errors = append(errors, finding(rules.ApplicationUnhealthy, "Application %s/%s is not healthy (%s): %s",
+				app.GetNamespace(), app.GetName(), reportedOnly(reported.health), reported.message))
 		}
+	case healthStr != "Healthy":
+		healthMessage, _, _ := unstructured.NestedString(app.Object, "status", "health", "message")
+		errors = append(errors, finding(rules.ApplicationUnhealthy, "Application %s/%s is not healthy (%s): %s",
+			app.GetNamespace(), app.GetName(), statusSources(healthStr, reported.health), healthMessage))
+	case reported.health != "" && reported.health != healthStr:
+		errors = append(errors, staleStatusFinding(app, "health", healthStr, reported.health))
 	}
 
-	// Check sync status
-	sync, found, err := unstructured.NestedFieldNoCopy(app.Object, "status", "sync", "status")
-	if err == nil && found {
-		if syncStr, ok := sync.(string); ok && syncStr != "Synced" {
-			errors = append(errors, &v1.ErrorDetail{
-				Text: fmt.Sprintf("Application %s/%s is not synced (status: %s)",
-					app.GetNamespace(), app.GetName(), syncStr),
-			})
+	// Check sync status, with the same fallback
+	syncStr, found, err := unstructured.NestedString(app.Object, "status", "sync", "status")
+	switch {
+	case err != nil || !found || syncStr == "":
+		if reported.sync != "" && reported.sync != "Synced" {
+			errors = append(errors, finding(rules.ApplicationOutOfSync, "Application %s/%s is not synced (%s)",
+				app.GetNamespace(), app.GetName(), reportedOnly(reported.sync)))
 		}
+	case syncStr != "Synced":
+		errors = append(errors, finding(rules.ApplicationOutOfSync, "Application %s/%s is not synced (%s)",
+			app.GetNamespace(), app.GetName(), statusSources(syncStr, reported.sync)))
+	case reported.sync != "" && reported.sync != syncStr:
+		errors = append(errors, staleStatusFinding(app, "sync", syncStr, reported.sync))
 	}
 
 	// Check for operation failures
 	operationPhase, found, err := unstructured.NestedString(app.Object, "status", "operationState", "phase")
 	if err == nil && found && operationPhase == "Failed" {
 		operationMessage, _, _ := unstructured.NestedString(app.Object, "status", "operationState", "message")
-		errors = append(errors, &v1.ErrorDetail{
-			Text: fmt.Sprintf("Application %s/%s has failed operation: %s",
-				app.GetNamespace(), app.GetName(), operationMessage),
-		})
+		errors = append(errors, finding(rules.ApplicationOperationFailed, "Application %s/%s has failed operation: %s",
+			app.GetNamespace(), app.GetName(), operationMessage))
 	}
 
 	return errors
 }
 
+// statusSources describes a status of the live Application, together with the status
+// reported in the ApplicationSet's applicationStatus when there is one
+func statusSources(live, reported string) string {
+	switch reported {
+	case "":
+		return fmt.Sprintf("status: %s", live)
+	case live:
+		return fmt.Sprintf("status: %s, also reported by the ApplicationSet status", live)
+	default:
+		return fmt.Sprintf("status: %s, but the ApplicationSet status reports %s and is stale", live, reported)
+	}
+}
+
+// reportedOnly describes a status known only from the ApplicationSet's applicationStatus,
+// because the live Application does not report it
+func reportedOnly(reported string) string {
+	return fmt.Sprintf("status: %s, reported by the ApplicationSet status; the live Application has no status yet", reported)
+}
+
+// staleStatusFinding reports an applicationStatus entry that claims a problem the live Application no longer has
+func staleStatusFinding(app *unstructured.Unstructured, field, live, reported string) *v1.ErrorDetail {
+	return finding(rules.StaleApplicationStatus, "ApplicationSet status reports %s %s for Application %s/%s, but the live Application is %s",
+		field, reported, app.GetNamespace(), app.GetName(), live)
+}
+
 // getApplicationSetStatus extracts status information from ApplicationSet
 func (a *Handler) getApplicationSetStatus(appSet *unstructured.Unstructured) []string {
 	var statusDetails []string
@@ -88,27 +137,52 @@ func (a *Handler) getApplicationSetStatus(appSet *unstructured.Unstructured) []s
 	return statusDetails
 }
 
-// analyzeApplicationSet performs detailed analysis of a single ApplicationSet
-func (a *Handler) analyzeApplicationSet(ctx context.Context, appSet *unstructured.Unstructured) []*v1.ErrorDetail {
+// analyzeApplicationSet performs detailed analysis of a single ApplicationSet and
+// returns its findings along with the number of suppressed findings
+func (a *Handler) analyzeApplicationSet(ctx context.Context, appSet *unstructured.Unstructured, apps *applicationIndex, stats *runStats) ([]*v1.ErrorDetail, int) {
 	var errors []*v1.ErrorDetail
+	namespace := appSet.GetNamespace()
+	suppressions := a.newSuppressor(appSet)
+	logger := logging.FromContext(ctx).With("namespace", namespace, "applicationSet", appSet.GetName())
+	logger.Debug("Analyzing ApplicationSet")
+	defer func() {
+		logger.Debug("Analyzed ApplicationSet", "findings", len(errors), "suppressed", suppressions.suppressed)
+	}()
 
 	// Check 1: ApplicationSet conditions
-	conditionErrors := a.checkConditions(appSet)
-	errors = append(errors, conditionErrors...)
+	if a.config.CheckEnabled(config.CheckConditions) {
+		conditionErrors := suppressions.filter(a.checkConditions(appSet))
+		stats.addFindings(config.CheckConditions, namespace, len(conditionErrors))
+		errors = append(errors, conditionErrors...)
+	}
 
 	// Check 2: Progressing state
-	progressingErrors := a.checkProgressingState(appSet)
-	errors = append(errors, progressingErrors...)
+	if a.config.CheckEnabled(config.CheckProgressing) {
+		progressingErrors := suppressions.filter(a.checkProgressingState(appSet))
+		stats.addFindings(config.CheckProgressing, namespace, len(progressingErrors))
+		errors = append(errors, progressingErrors...)
+	}
 
 	// Check 3: Generator issues
-	generatorErrors := a.analyzeGenerators(appSet)
-	errors = append(errors, generatorErrors...)
+	if a.config.CheckEnabled(config.CheckGenerators) {
+		generatorErrors := a.analyzeGenerators(ctx, appSet)
+		// A skipped check leaves the run incomplete even if its finding is suppressed
+		if hasRule(generatorErrors, rules.GeneratorReferenceUnverified) {
+			stats.markIncomplete()
+		}
+		generatorErrors = suppressions.filter(generatorErrors)
+		stats.addFindings(config.CheckGenerators, namespace, len(generatorErrors))
+		errors = append(errors, generatorErrors...)
+	}
 
 	// Check 4: Generated applications status
-	appErrors := a.analyzeGeneratedApplications(ctx, appSet)
-	errors = append(errors, appErrors...)
+	if a.config.CheckEnabled(config.CheckApplications) {
+		appErrors := suppressions.filter(a.analyzeGeneratedApplications(appSet, apps, suppressions, stats))
+		stats.addFindings(config.CheckApplications, namespace, len(appErrors))
+		errors = append(errors, appErrors...)
+	}
 
-	return errors
+	return errors, suppressions.suppressed
 }
 
 // checkConditions analyzes ApplicationSet conditions
@@ -134,24 +208,18 @@ func (a *Handler) checkConditions(appSet *unstructured.Unstructured) []*v1.Error
 		switch condType {
 		case "ErrorOccurred":
 			if condStatus == "True" {
-				errors = append(errors, &v1.ErrorDetail{
-					Text: fmt.Sprintf("ApplicationSet %s/%s has error condition: %s",
-						appSet.GetNamespace(), appSet.GetName(), condMessage),
-				})
+				errors = append(errors, finding(rules.ErrorOccurred, "ApplicationSet %s/%s has error condition: %s",
+					appSet.GetNamespace(), appSet.GetName(), condMessage))
 			}
 		case "ParametersGenerated":
 			if condStatus == "False" {
-				errors = append(errors, &v1.ErrorDetail{
-					Text: fmt.Sprintf("ApplicationSet %s/%s failed to generate parameters: %s",
-						appSet.GetNamespace(), appSet.GetName(), condMessage),
-				})
+				errors = append(errors, finding(rules.ParametersNotGenerated, "ApplicationSet %s/%s failed to generate parameters: %s",
+					appSet.GetNamespace(), appSet.GetName(), condMessage))
 			}
 		case "ResourcesUpToDate":
 			if condStatus == "False" {
-				errors = append(errors, &v1.ErrorDetail{
-					Text: fmt.Sprintf("ApplicationSet %s/%s resources are not up to date: %s",
-						appSet.GetNamespace(), appSet.GetName(), condMessage),
-				})
+				errors = append(errors, finding(rules.ResourcesNotUpToDate, "ApplicationSet %s/%s resources are not up to date: %s",
+					appSet.GetNamespace(), appSet.GetName(), condMessage))
 			}
 		}
 	}
@@ -179,77 +247,87 @@ func (a *Handler) checkProgressingState(appSet *unstructured.Unstructured) []*v1
 		condMessage, _ := condition["message"].(string)
 
 		if condType == "Progressing" && condStatus == "True" {
-			errors = append(errors, &v1.ErrorDetail{
-				Text: fmt.Sprintf("ApplicationSet %s/%s is in progressing state: %s",
-					appSet.GetNamespace(), appSet.GetName(), condMessage),
-			})
+			if !a.progressingTimeoutExceeded(condition) {
+				continue
+			}
+			errors = append(errors, finding(rules.Progressing, "ApplicationSet %s/%s is in progressing state: %s",
+				appSet.GetNamespace(), appSet.GetName(), condMessage))
 		}
 	}
 
 	return errors
 }
 
+// progressingTimeoutExceeded reports whether a Progressing condition has lasted longer
+// than the configured threshold. Conditions without a parseable lastTransitionTime are
+// always reported.
+func (a *Handler) progressingTimeoutExceeded(condition map[string]interface{}) bool {
+	timeout := a.config.Checks.Thresholds.ProgressingTimeout.Duration
+	if timeout <= 0 {
+		return true
+	}
+	lastTransition, _ := condition["lastTransitionTime"].(string)
+	since, err := time.Parse(time.RFC3339, lastTransition)
+	if err != nil {
+		return true
+	}
+	return time.Since(since) >= timeout
+}
+
 // analyzeGenerators checks for issues in ApplicationSet generators
-func (a *Handler) analyzeGenerators(appSet *unstructured.Unstructured) []*v1.ErrorDetail {
+func (a *Handler) analyzeGenerators(ctx context.Context, appSet *unstructured.Unstructured) []*v1.ErrorDetail {
 	var errors []*v1.ErrorDetail
 
 	generators, found, err := unstructured.NestedSlice(appSet.Object, "spec", "generators")
 	if err != nil {
-		errors = append(errors, &v1.ErrorDetail{
-			Text: fmt.Sprintf("ApplicationSet %s/%s has invalid generators configuration: %v",
-				appSet.GetNamespace(), appSet.GetName(), err),
-		})
+		errors = append(errors, finding(rules.InvalidGenerators, "ApplicationSet %s/%s has invalid generators configuration: %v",
+			appSet.GetNamespace(), appSet.GetName(), err))
 		return errors
 	}
 
 	if !found || len(generators) == 0 {
-		errors = append(errors, &v1.ErrorDetail{
-			Text: fmt.Sprintf("ApplicationSet %s/%s has no generators defined",
-				appSet.GetNamespace(), appSet.GetName()),
-		})
+		errors = append(errors, finding(rules.NoGenerators, "ApplicationSet %s/%s has no generators defined",
+			appSet.GetNamespace(), appSet.GetName()))
 		return errors
 	}
 
 	// Check each generator
 	for i, gen := range generators {
-		generator, ok := gen.(map[string]interface{})
-		if !ok {
-			errors = append(errors, &v1.ErrorDetail{
-				Text: fmt.Sprintf("ApplicationSet %s/%s has invalid generator at index %d",
-					appSet.GetNamespace(), appSet.GetName(), i),
-			})
-			continue
-		}
+		errors = append(errors, a.validateGenerator(ctx, appSet, gen, fmt.Sprintf("index %d", i), 1)...)
+	}
 
-		// Check if generator is empty
-		if len(generator) == 0 {
-			errors = append(errors, &v1.ErrorDetail{
-				Text: fmt.Sprintf("ApplicationSet %s/%s has empty generator at index %d",
-					appSet.GetNamespace(), appSet.GetName(), i),
-			})
-			continue
-		}
+	return errors
+}
 
-		// Check specific generator types
-		genErrors := a.validateGeneratorType(appSet, generator, i)
-		errors = append(errors, genErrors...)
+// validateGenerator checks a generator at location, nested depth levels deep, and the
+// generators it combines
+func (a *Handler) validateGenerator(ctx context.Context, appSet *unstructured.Unstructured, gen interface{}, location string, depth int) []*v1.ErrorDetail {
+	generator, ok := gen.(map[string]interface{})
+	if !ok {
+		return []*v1.ErrorDetail{finding(rules.InvalidGenerator, "ApplicationSet %s/%s has invalid generator at %s",
+			appSet.GetNamespace(), appSet.GetName(), location)}
 	}
 
-	return errors
+	// Check if generator is empty
+	if len(generator) == 0 {
+		return []*v1.ErrorDetail{finding(rules.EmptyGenerator, "ApplicationSet %s/%s has empty generator at %s",
+			appSet.GetNamespace(), appSet.GetName(), location)}
+	}
+
+	// Check specific generator types
+	return a.validateGeneratorType(ctx, appSet, generator, location, depth)
 }
 
 // validateGeneratorType validates specific generator types
-func (a *Handler) validateGeneratorType(appSet *unstructured.Unstructured, generator map[string]interface{}, index int) []*v1.ErrorDetail {
+func (a *Handler) validateGeneratorType(ctx context.Context, appSet *unstructured.Unstructured, generator map[string]interface{}, location string, depth int) []*v1.ErrorDetail {
 	var errors []*v1.ErrorDetail
 
 	// Check Git generator
 	if gitGen, found := generator["git"]; found {
 		if gitMap, ok := gitGen.(map[string]interface{}); ok {
 			if repoURL, exists := gitMap["repoURL"]; !exists || repoURL == "" {
-				errors = append(errors, &v1.ErrorDetail{
-					Text: fmt.Sprintf("ApplicationSet %s/%s Git generator at index %d has empty repoURL",
-						appSet.GetNamespace(), appSet.GetName(), index),
-				})
+				errors = append(errors, finding(rules.GitGeneratorNoRepoURL, "ApplicationSet %s/%s Git generator at %s has empty repoURL",
+					appSet.GetNamespace(), appSet.GetName(), location))
 			}
 		}
 	}
@@ -261,16 +339,12 @@ func (a *Handler) validateGeneratorType(appSet *unstructured.Unstructured, gener
 			_, hasElementsYaml := listMap["elementsYaml"]
 
 			if !hasElements && !hasElementsYaml {
-				errors = append(errors, &v1.ErrorDetail{
-					Text: fmt.Sprintf("ApplicationSet %s/%s List generator at index %d has no elements or elementsYaml",
-						appSet.GetNamespace(), appSet.GetName(), index),
-				})
+				errors = append(errors, finding(rules.ListGeneratorNoElements, "ApplicationSet %s/%s List generator at %s has no elements or elementsYaml",
+					appSet.GetNamespace(), appSet.GetName(), location))
 			} else if hasElements {
 				if elemSlice, ok := elements.([]interface{}); ok && len(elemSlice) == 0 {
-					errors = append(errors, &v1.ErrorDetail{
-						Text: fmt.Sprintf("ApplicationSet %s/%s List generator at index %d has empty elements array",
-							appSet.GetNamespace(), appSet.GetName(), index),
-					})
+					errors = append(errors, finding(rules.ListGeneratorEmptyElements, "ApplicationSet %s/%s List generator at %s has empty elements array",
+						appSet.GetNamespace(), appSet.GetName(), location))
 				}
 			}
 		}
@@ -283,29 +357,60 @@ func (a *Handler) validateGeneratorType(appSet *unstructured.Unstructured, gener
 			values, hasValues := clusterMap["values"]
 
 			if !hasSelector && !hasValues {
-				errors = append(errors, &v1.ErrorDetail{
-					Text: fmt.Sprintf("ApplicationSet %s/%s Cluster generator at index %d has no selector or values",
-						appSet.GetNamespace(), appSet.GetName(), index),
-				})
+				errors = append(errors, finding(rules.ClusterGeneratorUnfiltered, "ApplicationSet %s/%s Cluster generator at %s has no selector or values",
+					appSet.GetNamespace(), appSet.GetName(), location))
 			} else if hasValues {
 				if valuesMap, ok := values.(map[string]interface{}); ok && len(valuesMap) == 0 {
-					errors = append(errors, &v1.ErrorDetail{
-						Text: fmt.Sprintf("ApplicationSet %s/%s Cluster generator at index %d has empty values",
-							appSet.GetNamespace(), appSet.GetName(), index),
-					})
+					errors = append(errors, finding(rules.ClusterGeneratorEmptyValues, "ApplicationSet %s/%s Cluster generator at %s has empty values",
+						appSet.GetNamespace(), appSet.GetName(), location))
 				}
 			}
 		}
 	}
 
+	// Check Matrix generator
+	if matrixGen, found := generator["matrix"]; found {
+		errors = append(errors, a.validateMatrixGenerator(ctx, appSet, matrixGen, location, depth)...)
+	}
+
+	// Check Merge generator
+	if mergeGen, found := generator["merge"]; found {
+		errors = append(errors, a.validateMergeGenerator(ctx, appSet, mergeGen, location, depth)...)
+	}
+
+	// Check SCM Provider generator
+	if scmGen, found := generator["scmProvider"]; found {
+		errors = append(errors, a.validateSCMProviderGenerator(ctx, appSet, scmGen, location)...)
+	}
+
+	// Check Pull Request generator
+	if prGen, found := generator["pullRequest"]; found {
+		errors = append(errors, a.validatePullRequestGenerator(ctx, appSet, prGen, location)...)
+	}
+
+	// Check ClusterDecisionResource generator
+	if cdrGen, found := generator["clusterDecisionResource"]; found {
+		errors = append(errors, a.validateClusterDecisionResourceGenerator(ctx, appSet, cdrGen, location)...)
+	}
+
+	// Check Plugin generator
+	if pluginGen, found := generator["plugin"]; found {
+		errors = append(errors, a.validatePluginGenerator(ctx, appSet, pluginGen, location)...)
+	}
+
 	return errors
 }
 
-// analyzeGeneratedApplications checks the status of applications generated by the ApplicationSet
-func (a *Handler) analyzeGeneratedApplications(ctx context.Context, appSet *unstructured.Unstructured) []*v1.ErrorDetail {
+// analyzeGeneratedApplications checks the status of applications generated by the ApplicationSet,
+// dropping the findings of live Applications that are suppressed by their ignore annotation.
+// Applications that are both listed in status.applicationStatus and found live are reported
+// once, from the live object, so each broken Application appears a single time.
+func (a *Handler) analyzeGeneratedApplications(appSet *unstructured.Unstructured, apps *applicationIndex, suppressions *suppressor, stats *runStats) []*v1.ErrorDetail {
 	var errors []*v1.ErrorDetail
 
-	// First, check the applicationStatus in the ApplicationSet status
+	// Collect what the ApplicationSet status reports for each generated application
+	var reportedNames []string
+	reported := make(map[string]*reportedStatus)
 	appStatus, found, err := unstructured.NestedSlice(appSet.Object, "status", "applicationStatus")
 	if err == nil && found {
 		for _, app := range appStatus {
@@ -313,52 +418,67 @@ func (a *Handler) analyzeGeneratedApplications(ctx context.Context, appSet *unst
 			if !ok {
 				continue
 			}
-
 			appName, _ := appInfo["application"].(string)
-			health, _ := appInfo["health"].(string)
-			sync, _ := appInfo["sync"].(string)
-			message, _ := appInfo["message"].(string)
-
-			// Check for unhealthy applications
-			if health != "" && health != "Healthy" {
-				errors = append(errors, &v1.ErrorDetail{
-					Text: fmt.Sprintf("Generated Application %s is not healthy (status: %s): %s",
-						appName, health, message),
-				})
-			}
+			status := &reportedStatus{}
+			status.health, _ = appInfo["health"].(string)
+			status.sync, _ = appInfo["sync"].(string)
+			status.message, _ = appInfo["message"].(string)
+			reportedNames = append(reportedNames, appName)
+			reported[appName] = status
+		}
+	}
 
-			// Check for unsynced applications
-			if sync != "" && sync != "Synced" {
-				errors = append(errors, &v1.ErrorDetail{
-					Text: fmt.Sprintf("Generated Application %s is not synced (status: %s)",
-						appName, sync),
-				})
-			}
+	// Join the live Application resources from the run's Application index for more detailed status
+	var applications []*unstructured.Unstructured
+	live := make(map[string]bool)
+	if apps.err == nil {
+		applications = apps.forApplicationSet(appSet)
+		stats.addApplications(len(applications))
+		for _, app := range applications {
+			live[app.GetName()] = true
 		}
 	}
 
-	// Also try to list actual Application resources to get more detailed status
-	appLabelSelector := fmt.Sprintf("argocd.argoproj.io/application-set-name=%s", appSet.GetName())
-	applications, err := a.dynamicClient.Resource(applicationGVR).Namespace(appSet.GetNamespace()).List(ctx, metav1.ListOptions{
-		LabelSelector: appLabelSelector,
-	})
+	// Fall back to the ApplicationSet status for applications that were not found live.
+	// With Application selectors configured a missing Application may only be filtered
+	// out, so the fallback would report Applications outside the selection.
+	applicationsFiltered := a.config.ApplicationLabelSelector != "" || a.config.ApplicationFieldSelector != ""
+	for _, appName := range reportedNames {
+		if live[appName] || applicationsFiltered {
+			continue
+		}
+		status := reported[appName]
+
+		// Check for unhealthy applications
+		if status.health != "" && status.health != "Healthy" {
+			errors = append(errors, finding(rules.GeneratedApplicationUnhealthy, "Generated Application %s is not healthy (status: %s): %s",
+				appName, status.health, status.message))
+		}
 
-	if err != nil {
-		// Don't fail if we can't list applications - the applicationStatus check above should be sufficient
+		// Check for unsynced applications
+		if status.sync != "" && status.sync != "Synced" {
+			errors = append(errors, finding(rules.GeneratedApplicationOutOfSync, "Generated Application %s is not synced (status: %s)",
+				appName, status.sync))
+		}
+	}
+
+	if apps.err != nil {
 		return errors
 	}
 
-	if len(applications.Items) == 0 && len(appStatus) == 0 {
-		errors = append(errors, &v1.ErrorDetail{
-			Text: fmt.Sprintf("ApplicationSet %s/%s has no generated applications",
-				appSet.GetNamespace(), appSet.GetName()),
-		})
+	// With Application selectors configured an empty list only means nothing matched
+	if len(applications) == 0 && len(appStatus) == 0 && !applicationsFiltered {
+		errors = append(errors, finding(rules.NoGeneratedApplications, "ApplicationSet %s/%s has no generated applications",
+			appSet.GetNamespace(), appSet.GetName()))
 	}
 
 	// Analyze individual applications for more detailed issues
-	for _, app := range applications.Items {
-		appErrors := a.analyzeApplication(&app)
-		errors = append(errors, appErrors...)
+	now := time.Now()
+	previews := newPreviewPruning(appSet)
+	for _, app := range applications {
+		appErrors := a.analyzeApplication(app, reported[app.GetName()])
+		appErrors = append(appErrors, previews.stale(appSet, app, now)...)
+		errors = append(errors, suppressions.filterApplication(app, appErrors)...)
 	}
 
 	return errors
@@ -0,0 +1,163 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ranakan19/custom-analyzer/pkg/config"
+	"github.com/ranakan19/custom-analyzer/pkg/metrics"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// informerCache keeps the ApplicationSets and Applications in scope up to date
+// through watches, so that Run can be served without listing from the API server
+type informerCache struct {
+	appSets []informers.GenericInformer
+	apps    []informers.GenericInformer
+	synced  atomic.Bool
+
+	// reviews caches the permission preflight, so Runs served from the cache do not
+	// issue SelfSubjectAccessReviews each time
+	reviewsMu sync.Mutex
+	reviews   map[string]accessReview
+}
+
+// StartCache switches the handler to watch mode: ApplicationSets and, if the
+// applications check is enabled, Applications are watched with dynamic informers
+// until ctx is cancelled. Run is served from the cache once it has synced and
+// lists from the API server until then, or for good if the API server does not serve
+// ApplicationSets when the cache is started. StartCache must be called before serving.
+func (a *Handler) StartCache(ctx context.Context) error {
+	if len(a.config.KubeContexts) > 0 {
+		for _, c := range a.clusterHandlers() {
+			if err := c.handler.StartCache(ctx); err != nil {
+				return fmt.Errorf("cluster %s: %v", c.name, err)
+			}
+		}
+		return nil
+	}
+
+	if err := a.initializeClient(); err != nil {
+		return err
+	}
+
+	// Informers for resources that are not served yet keep retrying until the CRDs are installed
+	resources, err := a.servedResources()
+	if err != nil {
+		a.logger.Warn("Failed to discover Argo CD resources, assuming default versions", "error", err)
+		resources = servedResources{applicationSet: applicationSetGVR, application: applicationGVR}
+	} else if resources.applicationSet.Empty() {
+		// An informer for a missing CRD would never sync and keep the handler unhealthy,
+		// so the handler keeps listing from the API server, which picks up a later install
+		a.logger.Warn("ApplicationSet resource is not served by the API server, not starting the informer cache", "group", applicationSetGVR.Group)
+		return nil
+	}
+	watchApplications := a.config.CheckEnabled(config.CheckApplications)
+	if watchApplications && resources.application.Empty() {
+		// Likewise, the live Application checks report the missing resource instead
+		a.logger.Warn("Application resource is not served by the API server, not watching Applications", "group", applicationGVR.Group)
+		watchApplications = false
+	}
+
+	namespaces := a.config.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	resync := a.config.ResyncPeriod.Duration
+
+	c := &informerCache{reviews: make(map[string]accessReview)}
+	var hasSynced []cache.InformerSynced
+	for _, namespace := range namespaces {
+		appSets := dynamicinformer.NewFilteredDynamicInformer(a.dynamicClient, resources.applicationSet, namespace, resync, cache.Indexers{},
+			func(opts *metav1.ListOptions) {
+				opts.LabelSelector = a.config.LabelSelector
+				opts.FieldSelector = a.config.FieldSelector
+			})
+		c.appSets = append(c.appSets, appSets)
+		hasSynced = append(hasSynced, appSets.Informer().HasSynced)
+
+		if watchApplications {
+			apps := dynamicinformer.NewFilteredDynamicInformer(a.dynamicClient, resources.application, namespace, resync, cache.Indexers{},
+				func(opts *metav1.ListOptions) {
+					opts.LabelSelector = a.config.ApplicationLabelSelector
+					opts.FieldSelector = a.config.ApplicationFieldSelector
+				})
+			c.apps = append(c.apps, apps)
+			hasSynced = append(hasSynced, apps.Informer().HasSynced)
+		}
+	}
+
+	for _, informer := range append(append([]informers.GenericInformer{}, c.appSets...), c.apps...) {
+		go informer.Informer().Run(ctx.Done())
+	}
+
+	syncedGauge := metrics.CacheSynced.WithLabelValues(a.config.KubeContext)
+	syncedGauge.Set(0)
+	go func() {
+		if !cache.WaitForCacheSync(ctx.Done(), hasSynced...) {
+			return
+		}
+		c.synced.Store(true)
+		syncedGauge.Set(1)
+		a.logger.Info("Informer cache synced", "namespaces", len(namespaces))
+	}()
+
+	a.cache = c
+	return nil
+}
+
+// CacheSynced reports whether the informer cache, or the caches of all clusters, have
+// synced. It is always false when the handler is not in watch mode.
+func (a *Handler) CacheSynced() bool {
+	if len(a.config.KubeContexts) > 0 {
+		for _, c := range a.clusterHandlers() {
+			if !c.handler.CacheSynced() {
+				return false
+			}
+		}
+		return true
+	}
+	return a.cache != nil && a.cache.synced.Load()
+}
+
+// cachedApplicationSets returns the cached ApplicationSets in namespace/name order
+func (c *informerCache) cachedApplicationSets() ([]unstructured.Unstructured, error) {
+	return listInformers(c.appSets)
+}
+
+// cachedApplications returns the cached Applications in namespace/name order
+func (c *informerCache) cachedApplications() ([]unstructured.Unstructured, error) {
+	return listInformers(c.apps)
+}
+
+func listInformers(genericInformers []informers.GenericInformer) ([]unstructured.Unstructured, error) {
+	var items []unstructured.Unstructured
+	for _, informer := range genericInformers {
+		objects, err := informer.Lister().List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			u, ok := obj.(*unstructured.Unstructured)
+			if !ok {
+				return nil, fmt.Errorf("unexpected object type %T in informer cache", obj)
+			}
+			items = append(items, *u)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].GetNamespace() != items[j].GetNamespace() {
+			return items[i].GetNamespace() < items[j].GetNamespace()
+		}
+		return items[i].GetName() < items[j].GetName()
+	})
+	return items, nil
+}
@@ -0,0 +1,187 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var configMapGVR = corev1.SchemeGroupVersion.WithResource("configmaps")
+
+// validateClusterDecisionResourceGenerator checks that the ConfigMap of a
+// ClusterDecisionResource generator has the keys describing the duck-typed resource,
+// and that the resource it selects exists and has the status list of decisions
+func (a *Handler) validateClusterDecisionResourceGenerator(ctx context.Context, appSet *unstructured.Unstructured, cdrGen interface{}, location string) []*v1.ErrorDetail {
+	cdr, ok := cdrGen.(map[string]interface{})
+	if !ok {
+		return []*v1.ErrorDetail{finding(rules.InvalidGenerator, "ApplicationSet %s/%s has invalid generator at %s",
+			appSet.GetNamespace(), appSet.GetName(), location)}
+	}
+
+	configMap, errors := a.generatorConfigMap(ctx, appSet, "ClusterDecisionResource", location, cdr, "apiVersion", "kind", "statusListKey", "matchKey")
+	name, _ := cdr["name"].(string)
+	selector, hasSelector := cdr["labelSelector"].(map[string]interface{})
+	if name == "" && !hasSelector {
+		errors = append(errors, finding(rules.DuckTypeResourceMissing, "ApplicationSet %s/%s ClusterDecisionResource generator at %s has neither name nor labelSelector",
+			appSet.GetNamespace(), appSet.GetName(), location))
+	}
+	if configMap == nil || len(errors) > 0 {
+		return errors
+	}
+
+	data, _ := configMap.Object["data"].(map[string]interface{})
+	apiVersion, _ := data["apiVersion"].(string)
+	kind, _ := data["kind"].(string)
+	statusListKey, _ := data["statusListKey"].(string)
+	matchKey, _ := data["matchKey"].(string)
+
+	logger := logging.FromContext(ctx)
+	gvr, served, err := a.duckTypeResource(apiVersion, kind)
+	if err != nil {
+		logger.Debug("Failed to discover duck-typed resource, skipping check", "apiVersion", apiVersion, "kind", kind, "error", err)
+		return referenceCheck(appSet, "ClusterDecisionResource", location, "the resources of "+apiVersion, "discover the resources of "+apiVersion, err)
+	}
+	if !served {
+		errors := []*v1.ErrorDetail{finding(rules.DuckTypeResourceMissing, "ApplicationSet %s/%s ClusterDecisionResource generator at %s: ConfigMap %s/%s refers to %s of %s, which the API server does not serve",
+			appSet.GetNamespace(), appSet.GetName(), location, configMap.GetNamespace(), configMap.GetName(), kind, apiVersion)}
+		markSensitive(errors, []string{configMap.GetNamespace(), configMap.GetName()})
+		return errors
+	}
+
+	// Argo CD looks up the duck-typed resource in the ApplicationSet's namespace
+	namespace := appSet.GetNamespace()
+	var resources []unstructured.Unstructured
+	verb := "list"
+	if name != "" {
+		verb = "get"
+		var resource *unstructured.Unstructured
+		err = a.callWithRetry(ctx, gvr, "get", func() error {
+			var err error
+			resource, err = a.dynamicClient.Resource(gvr).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+			return err
+		})
+		if apierrors.IsNotFound(err) {
+			return []*v1.ErrorDetail{finding(rules.DuckTypeResourceMissing, "ApplicationSet %s/%s ClusterDecisionResource generator at %s references %s %s/%s, which does not exist",
+				appSet.GetNamespace(), appSet.GetName(), location, kind, namespace, name)}
+		}
+		if resource != nil {
+			resources = append(resources, *resource)
+		}
+	} else {
+		var labelSelector metav1.LabelSelector
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(selector, &labelSelector); err != nil {
+			return []*v1.ErrorDetail{finding(rules.DuckTypeResourceMissing, "ApplicationSet %s/%s ClusterDecisionResource generator at %s has invalid labelSelector: %v",
+				appSet.GetNamespace(), appSet.GetName(), location, err)}
+		}
+		parsed, parseErr := metav1.LabelSelectorAsSelector(&labelSelector)
+		if parseErr != nil {
+			return []*v1.ErrorDetail{finding(rules.DuckTypeResourceMissing, "ApplicationSet %s/%s ClusterDecisionResource generator at %s has invalid labelSelector: %v",
+				appSet.GetNamespace(), appSet.GetName(), location, parseErr)}
+		}
+		var list *unstructured.UnstructuredList
+		list, err = a.list(ctx, gvr, namespace, metav1.ListOptions{LabelSelector: parsed.String()})
+		if err == nil && len(list.Items) == 0 {
+			return []*v1.ErrorDetail{finding(rules.DuckTypeResourceMissing, "ApplicationSet %s/%s ClusterDecisionResource generator at %s: no %s in namespace %s matches labelSelector %s",
+				appSet.GetNamespace(), appSet.GetName(), location, kind, namespace, parsed.String())}
+		}
+		if list != nil {
+			resources = list.Items
+		}
+	}
+	if err != nil {
+		logger.Debug("Failed to get duck-typed resource, skipping check", "resource", gvr.String(), "namespace", namespace, "error", err)
+		reference := fmt.Sprintf("%s %s/%s", kind, namespace, name)
+		if name == "" {
+			reference = fmt.Sprintf("the %s selected by labelSelector in namespace %s", kind, namespace)
+		}
+		return referenceCheck(appSet, "ClusterDecisionResource", location, reference, fmt.Sprintf("%s %s %s", verb, gvr.GroupResource(), namespaceScope(namespace)), err)
+	}
+
+	// Resources selected by labels are named in findings but not in the ApplicationSet spec
+	var names []string
+	for _, resource := range resources {
+		names = append(names, resource.GetName())
+		decisions, found, _ := unstructured.NestedSlice(resource.Object, "status", statusListKey)
+		if !found {
+			errors = append(errors, finding(rules.DuckTypeStatusListMissing, "ApplicationSet %s/%s ClusterDecisionResource generator at %s: %s %s/%s has no status.%s list",
+				appSet.GetNamespace(), appSet.GetName(), location, kind, namespace, resource.GetName(), statusListKey))
+			continue
+		}
+		// An empty list only means that no cluster has been selected yet
+		matched := len(decisions) == 0
+		for _, d := range decisions {
+			decision, _ := d.(map[string]interface{})
+			if value, _ := decision[matchKey].(string); value != "" {
+				matched = true
+			}
+		}
+		if !matched {
+			errors = append(errors, finding(rules.DuckTypeStatusListMissing, "ApplicationSet %s/%s ClusterDecisionResource generator at %s: %s %s/%s has no %s in any status.%s entry",
+				appSet.GetNamespace(), appSet.GetName(), location, kind, namespace, resource.GetName(), matchKey, statusListKey))
+		}
+	}
+	markSensitive(errors, names)
+	return errors
+}
+
+// generatorConfigMap fetches the ConfigMap referenced by the configMapRef of a generator,
+// either a name or a {name} object, from the Argo CD namespace, and reports it if it is
+// missing or lacks any of keys, or if it cannot be read, marking the ConfigMap's
+// namespace and name sensitive. It returns nil if the ConfigMap is missing or cannot be read.
+func (a *Handler) generatorConfigMap(ctx context.Context, appSet *unstructured.Unstructured, kind, location string, spec map[string]interface{}, keys ...string) (*unstructured.Unstructured, []*v1.ErrorDetail) {
+	var name string
+	switch ref := spec["configMapRef"].(type) {
+	case string:
+		name = ref
+	case map[string]interface{}:
+		name, _ = ref["name"].(string)
+	}
+	if name == "" {
+		return nil, []*v1.ErrorDetail{finding(rules.GeneratorConfigMapMissing, "ApplicationSet %s/%s %s generator at %s has no configMapRef",
+			appSet.GetNamespace(), appSet.GetName(), kind, location)}
+	}
+
+	namespace := a.config.ArgoCDNamespace
+	var configMap *unstructured.Unstructured
+	err := a.callWithRetry(ctx, configMapGVR, "get", func() error {
+		var err error
+		configMap, err = a.dynamicClient.Resource(configMapGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		errors := []*v1.ErrorDetail{finding(rules.GeneratorConfigMapMissing, "ApplicationSet %s/%s %s generator at %s references ConfigMap %s/%s, which does not exist",
+			appSet.GetNamespace(), appSet.GetName(), kind, location, namespace, name)}
+		markSensitive(errors, []string{namespace, name})
+		return nil, errors
+	}
+	if err != nil {
+		logging.FromContext(ctx).Debug("Failed to get ConfigMap referenced by generator, skipping check", "configMap", name, "namespace", namespace, "error", err)
+		errors := referenceCheck(appSet, kind, location, fmt.Sprintf("ConfigMap %s/%s", namespace, name), "get configmaps "+namespaceScope(namespace), err)
+		markSensitive(errors, []string{namespace, name})
+		return nil, errors
+	}
+
+	data, _ := configMap.Object["data"].(map[string]interface{})
+	var missing []string
+	for _, key := range keys {
+		if value, _ := data[key].(string); value == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		errors := []*v1.ErrorDetail{finding(rules.GeneratorConfigMapKeyMissing, "ApplicationSet %s/%s %s generator at %s: ConfigMap %s/%s has no %s",
+			appSet.GetNamespace(), appSet.GetName(), kind, location, namespace, name, strings.Join(missing, ", "))}
+		markSensitive(errors, []string{namespace, name})
+		return configMap, errors
+	}
+	return configMap, nil
+}
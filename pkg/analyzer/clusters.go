@@ -0,0 +1,113 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/metrics"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	"k8s.io/client-go/dynamic"
+)
+
+// cluster is one of the clusters analyzed together when several kubeconfig contexts are configured
+type cluster struct {
+	name    string
+	handler *Handler
+}
+
+// WithClusterDynamicClient sets the dynamic client of the cluster behind a kubeconfig context for testing
+func (a *Analyzer) WithClusterDynamicClient(kubeContext string, client dynamic.Interface) *Analyzer {
+	if a.Handler.clusterClients == nil {
+		a.Handler.clusterClients = make(map[string]dynamic.Interface)
+	}
+	a.Handler.clusterClients[kubeContext] = client
+	return a
+}
+
+// clusterHandlers returns one handler per configured kubeconfig context, creating them on
+// first use. Each handler has its own clients and analyzes its cluster with the shared settings.
+func (a *Handler) clusterHandlers() []cluster {
+	a.clientMu.Lock()
+	defer a.clientMu.Unlock()
+
+	if a.clusters == nil {
+		for _, name := range a.config.KubeContexts {
+			cfg := *a.config
+			cfg.KubeContext = name
+			cfg.KubeContexts = nil
+			a.clusters = append(a.clusters, cluster{
+				name: name,
+				handler: &Handler{
+					config:        &cfg,
+					logger:        a.logger.With("cluster", name),
+					dynamicClient: a.clusterClients[name],
+				},
+			})
+		}
+	}
+	return a.clusters
+}
+
+// analyzeClusters analyzes every configured cluster concurrently and merges the results,
+// prefixing the message of each finding with the cluster it came from. It returns false
+// if the analysis of any cluster failed; the findings and counters of the other clusters
+// are still returned.
+func (a *Handler) analyzeClusters(ctx context.Context, stats *runStats) (*v1.Result, bool) {
+	clusters := a.clusterHandlers()
+	results := make([]*v1.Result, len(clusters))
+	succeeded := make([]bool, len(clusters))
+	clusterStats := make([]*runStats, len(clusters))
+
+	var wg sync.WaitGroup
+	for i, c := range clusters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clusterCtx := logging.NewContext(ctx, logging.FromContext(ctx).With("cluster", c.name))
+			clusterStats[i] = newRunStats(c.name)
+			results[i], succeeded[i] = c.handler.analyze(clusterCtx, clusterStats[i])
+		}()
+	}
+	wg.Wait()
+
+	ok := true
+	var errors []*v1.ErrorDetail
+	var details []string
+	for i, c := range clusters {
+		// Only the counters of analyzed clusters are recorded, so that a failed cluster
+		// does not prevent publishing those of the others
+		if succeeded[i] {
+			stats.add(clusterStats[i])
+			metrics.ObserveClusterRun(c.name, metrics.RunResultSuccess)
+		} else {
+			ok = false
+			metrics.ObserveClusterRun(c.name, metrics.RunResultError)
+		}
+		for _, e := range results[i].Error {
+			// Keep the rule tag first so that findings stay parseable
+			if id, severity, message, parsed := rules.Parse(e.Text); parsed {
+				rule := rules.Rule{ID: id, Severity: severity}
+				e.Text = rule.Format("[%s] %s", c.name, message)
+			} else {
+				e.Text = fmt.Sprintf("[%s] %s", c.name, e.Text)
+			}
+			errors = append(errors, e)
+		}
+		markSensitive(results[i].Error, []string{c.name})
+		details = append(details, fmt.Sprintf("Cluster %s:", c.name))
+		for _, line := range strings.Split(results[i].Details, "\n") {
+			details = append(details, "  "+line)
+		}
+	}
+
+	return &v1.Result{
+		Kind:    aggregateKind,
+		Name:    resultName,
+		Details: strings.Join(details, "\n"),
+		Error:   errors,
+	}, ok
+}
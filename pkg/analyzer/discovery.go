@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+)
+
+// servedResources holds the GVRs of the Argo CD resources served by the API server.
+// A zero GVR means the resource is not served.
+type servedResources struct {
+	applicationSet schema.GroupVersionResource
+	application    schema.GroupVersionResource
+}
+
+// servedResources discovers which versions of the ApplicationSet and Application
+// resources the API server serves. The result is cached once ApplicationSets are
+// found; until then discovery is repeated so that a later CRD install is picked up.
+// Without a discovery client the default versions are assumed to be served.
+func (a *Handler) servedResources() (servedResources, error) {
+	a.clientMu.Lock()
+	defer a.clientMu.Unlock()
+
+	if a.resources != nil {
+		return *a.resources, nil
+	}
+	if a.discoveryClient == nil {
+		return servedResources{applicationSet: applicationSetGVR, application: applicationGVR}, nil
+	}
+
+	resources, err := discoverResources(a.discoveryClient)
+	if err != nil {
+		return servedResources{}, err
+	}
+	if !resources.applicationSet.Empty() {
+		a.resources = &resources
+	}
+	return resources, nil
+}
+
+// discoverResources finds the served versions of the ApplicationSet and Application
+// resources, preferring the group's preferred version
+func discoverResources(client discovery.DiscoveryInterface) (servedResources, error) {
+	var resources servedResources
+
+	groups, err := client.ServerGroups()
+	if err != nil {
+		return resources, fmt.Errorf("failed to discover API groups: %v", err)
+	}
+
+	for _, group := range groups.Groups {
+		if group.Name != applicationSetGVR.Group {
+			continue
+		}
+		versions := []string{group.PreferredVersion.GroupVersion}
+		for _, version := range group.Versions {
+			if version.GroupVersion != group.PreferredVersion.GroupVersion {
+				versions = append(versions, version.GroupVersion)
+			}
+		}
+
+		for _, groupVersion := range versions {
+			list, err := client.ServerResourcesForGroupVersion(groupVersion)
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return resources, fmt.Errorf("failed to discover resources of %s: %v", groupVersion, err)
+			}
+			gv, err := schema.ParseGroupVersion(groupVersion)
+			if err != nil {
+				return resources, fmt.Errorf("invalid group version %q: %v", groupVersion, err)
+			}
+			for _, resource := range list.APIResources {
+				if resource.Name == applicationSetGVR.Resource && resources.applicationSet.Empty() {
+					resources.applicationSet = gv.WithResource(resource.Name)
+				}
+				if resource.Name == applicationGVR.Resource && resources.application.Empty() {
+					resources.application = gv.WithResource(resource.Name)
+				}
+			}
+		}
+	}
+	return resources, nil
+}
+
+// duckTypeResource resolves the resource serving kind in apiVersion, as configured for a
+// ClusterDecisionResource generator, and reports whether it is served. Without a
+// discovery client the resource is assumed to be the lower-case plural of the kind.
+func (a *Handler) duckTypeResource(apiVersion, kind string) (schema.GroupVersionResource, bool, error) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, nil
+	}
+	if a.discoveryClient == nil {
+		return gv.WithResource(strings.ToLower(kind) + "s"), true, nil
+	}
+
+	list, err := a.discoveryClient.ServerResourcesForGroupVersion(gv.String())
+	if apierrors.IsNotFound(err) {
+		return schema.GroupVersionResource{}, false, nil
+	}
+	if err != nil {
+		return schema.GroupVersionResource{}, false, fmt.Errorf("failed to discover resources of %s: %w", gv.String(), err)
+	}
+	for _, resource := range list.APIResources {
+		// Subresources such as placementdecisions/status share the kind of their parent
+		if resource.Kind == kind && !strings.Contains(resource.Name, "/") {
+			return gv.WithResource(resource.Name), true, nil
+		}
+	}
+	return schema.GroupVersionResource{}, false, nil
+}
@@ -0,0 +1,290 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxCombinationDepth is the deepest level at which Argo CD accepts matrix and merge
+// generators: a top-level one may combine another, whose children must be plain generators
+const maxCombinationDepth = 2
+
+// validateMatrixGenerator checks that a matrix generator combines exactly two valid
+// child generators, is not nested too deeply and that its children do not produce
+// parameters with the same name
+func (a *Handler) validateMatrixGenerator(ctx context.Context, appSet *unstructured.Unstructured, matrixGen interface{}, location string, depth int) []*v1.ErrorDetail {
+	matrix, ok := matrixGen.(map[string]interface{})
+	if !ok {
+		return []*v1.ErrorDetail{finding(rules.InvalidGenerator, "ApplicationSet %s/%s has invalid generator at %s",
+			appSet.GetNamespace(), appSet.GetName(), location)}
+	}
+	if depth > maxCombinationDepth {
+		return []*v1.ErrorDetail{finding(rules.GeneratorNestingTooDeep, "ApplicationSet %s/%s Matrix generator at %s is nested %d levels deep; Argo CD supports at most %d",
+			appSet.GetNamespace(), appSet.GetName(), location, depth, maxCombinationDepth)}
+	}
+
+	var errors []*v1.ErrorDetail
+	children, _ := matrix["generators"].([]interface{})
+	if len(children) != 2 {
+		errors = append(errors, finding(rules.MatrixGeneratorChildCount, "ApplicationSet %s/%s Matrix generator at %s has %d child generators; exactly 2 are required",
+			appSet.GetNamespace(), appSet.GetName(), location, len(children)))
+	}
+	for i, child := range children {
+		errors = append(errors, a.validateGenerator(ctx, appSet, child, fmt.Sprintf("%s, matrix child %d", location, i), depth+1)...)
+	}
+
+	if len(children) == 2 {
+		first := generatorParameters(children[0])
+		var collisions []string
+		for name := range generatorParameters(children[1]) {
+			if first[name] {
+				collisions = append(collisions, name)
+			}
+		}
+		if len(collisions) > 0 {
+			sort.Strings(collisions)
+			errors = append(errors, finding(rules.MatrixGeneratorParameterCollision, "ApplicationSet %s/%s Matrix generator at %s has children that both produce parameters %s; the second child's values override the first",
+				appSet.GetNamespace(), appSet.GetName(), location, strings.Join(collisions, ", ")))
+		}
+	}
+	return errors
+}
+
+// validateMergeGenerator checks that a merge generator has merge keys and a base
+// generator, is not nested too deeply, that its children are valid and produce the merge
+// keys, and that each secondary generator can override at least one base parameter set
+func (a *Handler) validateMergeGenerator(ctx context.Context, appSet *unstructured.Unstructured, mergeGen interface{}, location string, depth int) []*v1.ErrorDetail {
+	merge, ok := mergeGen.(map[string]interface{})
+	if !ok {
+		return []*v1.ErrorDetail{finding(rules.InvalidGenerator, "ApplicationSet %s/%s has invalid generator at %s",
+			appSet.GetNamespace(), appSet.GetName(), location)}
+	}
+	if depth > maxCombinationDepth {
+		return []*v1.ErrorDetail{finding(rules.GeneratorNestingTooDeep, "ApplicationSet %s/%s Merge generator at %s is nested %d levels deep; Argo CD supports at most %d",
+			appSet.GetNamespace(), appSet.GetName(), location, depth, maxCombinationDepth)}
+	}
+
+	var errors []*v1.ErrorDetail
+	var mergeKeys []string
+	keys, _ := merge["mergeKeys"].([]interface{})
+	for _, key := range keys {
+		if s, ok := key.(string); ok && s != "" {
+			mergeKeys = append(mergeKeys, s)
+		}
+	}
+	if len(mergeKeys) == 0 {
+		errors = append(errors, finding(rules.MergeGeneratorNoMergeKeys, "ApplicationSet %s/%s Merge generator at %s has no mergeKeys",
+			appSet.GetNamespace(), appSet.GetName(), location))
+	}
+
+	children, _ := merge["generators"].([]interface{})
+	if base, ok := firstChild(children); !ok || len(base) == 0 {
+		errors = append(errors, finding(rules.MergeGeneratorNoBase, "ApplicationSet %s/%s Merge generator at %s has no base generator",
+			appSet.GetNamespace(), appSet.GetName(), location))
+		return errors
+	}
+
+	baseValid := false
+	for i, child := range children {
+		childLocation := fmt.Sprintf("%s, merge child %d", location, i)
+		childErrors := a.validateGenerator(ctx, appSet, child, childLocation, depth+1)
+		errors = append(errors, childErrors...)
+		if invalidatesParameters(childErrors) || len(mergeKeys) == 0 {
+			continue
+		}
+
+		var missing []string
+		for _, key := range mergeKeys {
+			if produces, known := producesParameter(child, key); known && !produces {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			errors = append(errors, finding(rules.MergeGeneratorMissingMergeKey, "ApplicationSet %s/%s Merge generator at %s has child %d that does not produce merge keys %s",
+				appSet.GetNamespace(), appSet.GetName(), location, i, strings.Join(missing, ", ")))
+			continue
+		}
+
+		if i == 0 {
+			baseValid = true
+			continue
+		}
+		if !baseValid {
+			continue
+		}
+		base, baseKnown := mergeKeyValues(children[0], mergeKeys)
+		secondary, secondaryKnown := mergeKeyValues(child, mergeKeys)
+		if !baseKnown || !secondaryKnown || len(secondary) == 0 {
+			continue
+		}
+		matches := false
+		for value := range secondary {
+			matches = matches || base[value]
+		}
+		if !matches {
+			errors = append(errors, finding(rules.MergeGeneratorNoOverrides, "ApplicationSet %s/%s Merge generator at %s has child %d whose merge key values never match the base generator, so it overrides nothing",
+				appSet.GetNamespace(), appSet.GetName(), location, i))
+		}
+	}
+	return errors
+}
+
+// invalidatesParameters reports whether the findings of a generator show that it is
+// malformed, so that the parameters it produces are unknown. Other findings, such as a
+// missing secret, leave the parameters in its spec usable for the merge key checks.
+func invalidatesParameters(findings []*v1.ErrorDetail) bool {
+	for _, rule := range []rules.Rule{rules.InvalidGenerator, rules.EmptyGenerator, rules.GeneratorNestingTooDeep,
+		rules.MatrixGeneratorChildCount, rules.MergeGeneratorNoMergeKeys, rules.MergeGeneratorNoBase} {
+		if hasRule(findings, rule) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstChild returns the first of a combination generator's children as an object
+func firstChild(children []interface{}) (map[string]interface{}, bool) {
+	if len(children) == 0 {
+		return nil, false
+	}
+	child, ok := children[0].(map[string]interface{})
+	return child, ok
+}
+
+// producesParameter reports whether every parameter set of a generator contains the
+// parameter name; known is false if this cannot be decided without the cluster state
+func producesParameter(gen interface{}, name string) (produces, known bool) {
+	generator, _ := gen.(map[string]interface{})
+
+	if list, ok := generator["list"].(map[string]interface{}); ok {
+		if _, dynamic := list["elementsYaml"]; dynamic {
+			return false, false
+		}
+		elements, _ := list["elements"].([]interface{})
+		for _, element := range elements {
+			fields, _ := element.(map[string]interface{})
+			if _, ok := fields[name]; !ok {
+				return false, true
+			}
+		}
+		return true, true
+	}
+
+	if _, ok := generator["clusters"].(map[string]interface{}); ok {
+		if strings.HasPrefix(name, "metadata.") {
+			// Cluster labels and annotations are only known from the cluster secrets
+			return false, false
+		}
+		return generatorParameters(generator)[name], true
+	}
+
+	return false, false
+}
+
+// mergeKeyValues returns the distinct combinations of merge key values a generator
+// produces; known is false unless they are all literal values in the generator spec
+func mergeKeyValues(gen interface{}, mergeKeys []string) (map[string]bool, bool) {
+	generator, _ := gen.(map[string]interface{})
+	tuples := make(map[string]bool)
+
+	if list, ok := generator["list"].(map[string]interface{}); ok {
+		if _, dynamic := list["elementsYaml"]; dynamic {
+			return nil, false
+		}
+		elements, _ := list["elements"].([]interface{})
+		for _, element := range elements {
+			fields, _ := element.(map[string]interface{})
+			tuples[mergeKeyTuple(fields, mergeKeys, "")] = true
+		}
+		return tuples, true
+	}
+
+	if clusters, ok := generator["clusters"].(map[string]interface{}); ok {
+		// Only values are the same for every cluster
+		for _, key := range mergeKeys {
+			if !strings.HasPrefix(key, "values.") {
+				return nil, false
+			}
+		}
+		values, _ := clusters["values"].(map[string]interface{})
+		tuples[mergeKeyTuple(values, mergeKeys, "values.")] = true
+		return tuples, true
+	}
+
+	return nil, false
+}
+
+// mergeKeyTuple joins the values of the merge keys, with prefix trimmed, into a comparable string
+func mergeKeyTuple(fields map[string]interface{}, mergeKeys []string, prefix string) string {
+	values := make([]string, len(mergeKeys))
+	for i, key := range mergeKeys {
+		values[i] = fmt.Sprint(fields[strings.TrimPrefix(key, prefix)])
+	}
+	return strings.Join(values, "\x00")
+}
+
+// generatorParameters returns the names of the parameters a generator is statically
+// known to produce. Parameters that depend on cluster state or file contents are not included.
+func generatorParameters(gen interface{}) map[string]bool {
+	generator, _ := gen.(map[string]interface{})
+	params := make(map[string]bool)
+
+	if list, ok := generator["list"].(map[string]interface{}); ok {
+		elements, _ := list["elements"].([]interface{})
+		for _, element := range elements {
+			if fields, ok := element.(map[string]interface{}); ok {
+				for name := range fields {
+					params[name] = true
+				}
+			}
+		}
+	}
+
+	if clusters, ok := generator["clusters"].(map[string]interface{}); ok {
+		for _, name := range []string{"name", "nameNormalized", "server", "project"} {
+			params[name] = true
+		}
+		addValueParameters(params, clusters)
+	}
+
+	if git, ok := generator["git"].(map[string]interface{}); ok {
+		prefix, _ := git["pathParamPrefix"].(string)
+		if prefix != "" {
+			prefix += "."
+		}
+		names := []string{"path", "path.basename", "path.basenameNormalized"}
+		if _, files := git["files"]; files {
+			names = append(names, "path.filename", "path.filenameNormalized")
+		}
+		for _, name := range names {
+			params[prefix+name] = true
+		}
+		addValueParameters(params, git)
+	}
+
+	for _, combination := range []string{"matrix", "merge"} {
+		if spec, ok := generator[combination].(map[string]interface{}); ok {
+			children, _ := spec["generators"].([]interface{})
+			for _, child := range children {
+				for name := range generatorParameters(child) {
+					params[name] = true
+				}
+			}
+		}
+	}
+	return params
+}
+
+// addValueParameters adds the values.<key> parameters of a generator's values field
+func addValueParameters(params map[string]bool, generator map[string]interface{}) {
+	values, _ := generator["values"].(map[string]interface{})
+	for key := range values {
+		params["values."+key] = true
+	}
+}
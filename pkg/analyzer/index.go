@@ -0,0 +1,107 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// applicationSetNameLabel is set by the ApplicationSet controller on generated Applications
+const applicationSetNameLabel = "argocd.argoproj.io/application-set-name"
+
+// applicationIndex indexes Applications by the ApplicationSet that generated them,
+// so that all ApplicationSets of a run can be joined against a single Application list
+type applicationIndex struct {
+	// byOwner maps an ApplicationSet UID to the Applications it owns
+	byOwner map[types.UID][]*unstructured.Unstructured
+	// byLabel maps "namespace/name" of an ApplicationSet to the Applications labelled with its name
+	byLabel map[string][]*unstructured.Unstructured
+	// err is set if the Applications could not be listed
+	err error
+}
+
+func newApplicationIndex() *applicationIndex {
+	return &applicationIndex{
+		byOwner: make(map[types.UID][]*unstructured.Unstructured),
+		byLabel: make(map[string][]*unstructured.Unstructured),
+	}
+}
+
+// add indexes an Application by its ApplicationSet owner references and label
+func (idx *applicationIndex) add(app *unstructured.Unstructured) {
+	for _, owner := range app.GetOwnerReferences() {
+		if owner.Kind == "ApplicationSet" && strings.HasPrefix(owner.APIVersion, applicationSetGVR.Group+"/") {
+			idx.byOwner[owner.UID] = append(idx.byOwner[owner.UID], app)
+		}
+	}
+	if appSetName := app.GetLabels()[applicationSetNameLabel]; appSetName != "" {
+		key := app.GetNamespace() + "/" + appSetName
+		idx.byLabel[key] = append(idx.byLabel[key], app)
+	}
+}
+
+// forApplicationSet returns the Applications generated by appSet, matched by owner
+// reference or by label, without duplicates
+func (idx *applicationIndex) forApplicationSet(appSet *unstructured.Unstructured) []*unstructured.Unstructured {
+	var apps []*unstructured.Unstructured
+	seen := make(map[string]bool)
+	candidates := append([]*unstructured.Unstructured{}, idx.byOwner[appSet.GetUID()]...)
+	candidates = append(candidates, idx.byLabel[appSet.GetNamespace()+"/"+appSet.GetName()]...)
+	for _, app := range candidates {
+		key := app.GetNamespace() + "/" + app.GetName()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		apps = append(apps, app)
+	}
+	return apps
+}
+
+// buildApplicationIndex lists the Applications in scope once, page by page, and indexes
+// them by owning ApplicationSet. Listing errors are recorded on the index rather than
+// returned, since the ApplicationSet checks can still run without live Applications.
+// In watch mode the Applications are taken from the synced cache instead.
+func (a *Handler) buildApplicationIndex(ctx context.Context, gvr schema.GroupVersionResource) *applicationIndex {
+	idx := newApplicationIndex()
+	if gvr.Empty() {
+		idx.err = fmt.Errorf("%s/%s is not served by the API server", applicationGVR.Group, applicationGVR.Resource)
+		return idx
+	}
+	if a.CacheSynced() {
+		apps, err := a.cache.cachedApplications()
+		idx.err = err
+		for i := range apps {
+			idx.add(&apps[i])
+		}
+		return idx
+	}
+
+	opts := metav1.ListOptions{
+		LabelSelector: a.config.ApplicationLabelSelector,
+		FieldSelector: a.config.ApplicationFieldSelector,
+	}
+	addPage := func(page []unstructured.Unstructured) error {
+		for i := range page {
+			idx.add(&page[i])
+		}
+		return nil
+	}
+
+	namespaces := a.config.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	for _, namespace := range namespaces {
+		if err := a.listPages(ctx, gvr, namespace, opts, addPage); err != nil {
+			idx.err = err
+			return idx
+		}
+	}
+	return idx
+}
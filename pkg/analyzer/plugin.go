@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var serviceGVR = corev1.SchemeGroupVersion.WithResource("services")
+
+// defaultPluginSecret is the secret a plugin token of the form $<key> is read from
+const defaultPluginSecret = "argocd-secret"
+
+// validatePluginGenerator checks that the ConfigMap of a Plugin generator has a token
+// and a baseUrl, that the secret the token refers to exists and has the key, and that
+// the baseUrl is an http(s) URL whose in-cluster Service, if it names one, exists
+func (a *Handler) validatePluginGenerator(ctx context.Context, appSet *unstructured.Unstructured, pluginGen interface{}, location string) []*v1.ErrorDetail {
+	plugin, ok := pluginGen.(map[string]interface{})
+	if !ok {
+		return []*v1.ErrorDetail{finding(rules.InvalidGenerator, "ApplicationSet %s/%s has invalid generator at %s",
+			appSet.GetNamespace(), appSet.GetName(), location)}
+	}
+
+	configMap, errors := a.generatorConfigMap(ctx, appSet, "Plugin", location, plugin, "token", "baseUrl")
+	if configMap == nil {
+		return errors
+	}
+	data, _ := configMap.Object["data"].(map[string]interface{})
+	namespace := configMap.GetNamespace()
+
+	// Tokens of the form $<key> or $<secret>:<key> are read from secrets in the Argo CD namespace
+	if token, _ := data["token"].(string); strings.HasPrefix(token, "$") {
+		name, key := defaultPluginSecret, strings.TrimPrefix(token, "$")
+		if secret, secretKey, found := strings.Cut(key, ":"); found {
+			name, key = secret, secretKey
+		}
+		field := fmt.Sprintf("token of ConfigMap %s/%s", namespace, configMap.GetName())
+		errors = append(errors, a.checkSecret(ctx, appSet, "Plugin", location, field, namespace, name, key)...)
+	}
+
+	if baseURL, _ := data["baseUrl"].(string); baseURL != "" {
+		errors = append(errors, a.checkPluginBaseURL(ctx, appSet, location, configMap, baseURL)...)
+	}
+	// The plugin's ConfigMap is named in findings but not in the ApplicationSet spec
+	markSensitive(errors, []string{namespace, configMap.GetName()})
+	return errors
+}
+
+// checkPluginBaseURL checks that the baseUrl of a plugin ConfigMap is an http(s) URL whose
+// in-cluster Service, if it names one, exists, marking the URL, its host and the Service sensitive
+func (a *Handler) checkPluginBaseURL(ctx context.Context, appSet *unstructured.Unstructured, location string, configMap *unstructured.Unstructured, baseURL string) []*v1.ErrorDetail {
+	namespace := configMap.GetNamespace()
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		errors := []*v1.ErrorDetail{finding(rules.PluginUnreachable, "ApplicationSet %s/%s Plugin generator at %s: ConfigMap %s/%s has baseUrl %q, which is not an http(s) URL",
+			appSet.GetNamespace(), appSet.GetName(), location, namespace, configMap.GetName(), baseURL)}
+		markSensitive(errors, []string{baseURL})
+		return errors
+	}
+
+	serviceNamespace, serviceName, found := pluginService(u.Hostname(), namespace)
+	if !found {
+		return nil
+	}
+	err = a.callWithRetry(ctx, serviceGVR, "get", func() error {
+		_, err := a.dynamicClient.Resource(serviceGVR).Namespace(serviceNamespace).Get(ctx, serviceName, metav1.GetOptions{})
+		return err
+	})
+	var errors []*v1.ErrorDetail
+	if apierrors.IsNotFound(err) {
+		errors = append(errors, finding(rules.PluginUnreachable, "ApplicationSet %s/%s Plugin generator at %s: baseUrl %s of ConfigMap %s/%s points to Service %s/%s, which does not exist",
+			appSet.GetNamespace(), appSet.GetName(), location, baseURL, namespace, configMap.GetName(), serviceNamespace, serviceName))
+	} else if err != nil {
+		logging.FromContext(ctx).Debug("Failed to get plugin Service, skipping check", "service", serviceName, "namespace", serviceNamespace, "error", err)
+		errors = append(errors, referenceCheck(appSet, "Plugin", location, fmt.Sprintf("Service %s/%s", serviceNamespace, serviceName), "get services "+namespaceScope(serviceNamespace), err)...)
+	}
+	markSensitive(errors, []string{baseURL, u.Hostname(), serviceNamespace, serviceName})
+	return errors
+}
+
+// pluginService returns the Service a plugin host name resolves to inside the cluster:
+// a bare name in the Argo CD namespace, <name>.<namespace>, or <name>.<namespace>.svc
+// with an optional cluster domain. Other host names are not checked.
+func pluginService(host, argocdNamespace string) (string, string, bool) {
+	if net.ParseIP(host) != nil {
+		return "", "", false
+	}
+	labels := strings.Split(host, ".")
+	switch {
+	case len(labels) == 1 && host != "localhost":
+		return argocdNamespace, host, true
+	case len(labels) == 2:
+		return labels[1], labels[0], true
+	case len(labels) >= 3 && labels[2] == "svc":
+		return labels[1], labels[0], true
+	}
+	return "", "", false
+}
@@ -0,0 +1,164 @@
+package analyzer
+
+import (
+	"context"
+	"time"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// defaultPullRequestRequeue is how often Argo CD polls pull requests unless requeueAfterSeconds is set
+const defaultPullRequestRequeue = 30 * time.Minute
+
+// pullRequestProviders lists the providers supported by the Pull Request generator
+var pullRequestProviders = []generatorProvider{
+	{name: "github", required: []string{"owner", "repo"}, secretRefs: [][]string{{"tokenRef"}}, argocdSecretRefs: [][]string{{"appSecretName"}}},
+	{name: "gitlab", required: []string{"project"}, secretRefs: [][]string{{"tokenRef"}}},
+	{name: "gitea", required: []string{"owner", "repo", "api"}, secretRefs: [][]string{{"tokenRef"}}},
+	{name: "bitbucketServer", required: []string{"project", "repo", "api"}, secretRefs: [][]string{{"basicAuth", "passwordRef"}, {"bearerToken", "tokenRef"}}},
+	{name: "bitbucket", required: []string{"owner", "repo"}, secretRefs: [][]string{{"basicAuth", "passwordRef"}, {"bearerToken", "tokenRef"}}},
+	{name: "azuredevops", required: []string{"organization", "project", "repo"}, secretRefs: [][]string{{"tokenRef"}}},
+}
+
+// validatePullRequestGenerator checks that a Pull Request generator configures exactly
+// one provider with its required fields, that the secrets it references exist and have
+// the referenced keys, and that its label and branch filters are valid
+func (a *Handler) validatePullRequestGenerator(ctx context.Context, appSet *unstructured.Unstructured, prGen interface{}, location string) []*v1.ErrorDetail {
+	pr, ok := prGen.(map[string]interface{})
+	if !ok {
+		return []*v1.ErrorDetail{finding(rules.InvalidGenerator, "ApplicationSet %s/%s has invalid generator at %s",
+			appSet.GetNamespace(), appSet.GetName(), location)}
+	}
+
+	provider, errors := configuredProvider(appSet, "Pull Request", location, pr, pullRequestProviders, rules.PullRequestNotConfigured)
+	if provider == nil {
+		return errors
+	}
+	spec, _ := pr[provider.name].(map[string]interface{})
+	errors = append(errors, a.checkProvider(ctx, appSet, "Pull Request", location, provider, spec, rules.PullRequestMissingField)...)
+
+	labels, _ := spec["labels"].([]interface{})
+	for _, label := range labels {
+		if s, _ := label.(string); s == "" {
+			errors = append(errors, finding(rules.InvalidGeneratorFilter, "ApplicationSet %s/%s Pull Request generator at %s has an empty label in %s.labels",
+				appSet.GetNamespace(), appSet.GetName(), location, provider.name))
+		}
+	}
+
+	filters, _ := pr["filters"].([]interface{})
+	for i, f := range filters {
+		filter, _ := f.(map[string]interface{})
+		errors = append(errors, checkFilterPatterns(appSet, "Pull Request", location, i, filter, "branchMatch", "targetBranchMatch", "titleMatch")...)
+	}
+	return errors
+}
+
+// pullRequestRequeue returns the shortest polling interval of the Pull Request
+// generators of an ApplicationSet, including those nested in matrix and merge
+// generators, or false if it has none
+func pullRequestRequeue(appSet *unstructured.Unstructured) (time.Duration, bool) {
+	generators, _, _ := unstructured.NestedSlice(appSet.Object, "spec", "generators")
+	var requeue time.Duration
+	found := false
+
+	var walk func(generators []interface{})
+	walk = func(generators []interface{}) {
+		for _, gen := range generators {
+			generator, _ := gen.(map[string]interface{})
+			if pr, ok := generator["pullRequest"].(map[string]interface{}); ok {
+				interval := defaultPullRequestRequeue
+				if seconds, ok, _ := unstructured.NestedInt64(pr, "requeueAfterSeconds"); ok && seconds > 0 {
+					interval = time.Duration(seconds) * time.Second
+				}
+				if !found || interval < requeue {
+					requeue = interval
+				}
+				found = true
+			}
+			for _, combination := range []string{"matrix", "merge"} {
+				if spec, ok := generator[combination].(map[string]interface{}); ok {
+					children, _ := spec["generators"].([]interface{})
+					walk(children)
+				}
+			}
+		}
+	}
+	walk(generators)
+	return requeue, found
+}
+
+// previewPruning describes when the Applications generated by the Pull Request
+// generators of an ApplicationSet should have been pruned
+type previewPruning struct {
+	// requeue is the shortest pull request polling interval
+	requeue time.Duration
+	// desired holds the names of the Applications listed in status.resources
+	desired map[string]bool
+	// reconciled is the lastTransitionTime of the ResourcesUpToDate condition, since
+	// which status.resources has reflected the generated Applications
+	reconciled time.Time
+}
+
+// newPreviewPruning returns the pruning state of an ApplicationSet with a Pull Request
+// generator, or nil if it has none or its status does not tell when Applications left
+// status.resources, in which case stale ones cannot be told apart
+func newPreviewPruning(appSet *unstructured.Unstructured) *previewPruning {
+	requeue, found := pullRequestRequeue(appSet)
+	if !found {
+		return nil
+	}
+	resources, found, _ := unstructured.NestedSlice(appSet.Object, "status", "resources")
+	if !found {
+		return nil
+	}
+
+	pruning := &previewPruning{requeue: requeue, desired: make(map[string]bool)}
+	for _, r := range resources {
+		resource, _ := r.(map[string]interface{})
+		if name, _ := resource["name"].(string); name != "" {
+			pruning.desired[name] = true
+		}
+	}
+
+	conditions, _, _ := unstructured.NestedSlice(appSet.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, _ := c.(map[string]interface{})
+		if condition["type"] != "ResourcesUpToDate" || condition["status"] != "True" {
+			continue
+		}
+		lastTransition, _ := condition["lastTransitionTime"].(string)
+		if reconciled, err := time.Parse(time.RFC3339, lastTransition); err == nil {
+			pruning.reconciled = reconciled
+			return pruning
+		}
+	}
+	return nil
+}
+
+// stale reports a generated Application that the ApplicationSet no longer lists in
+// status.resources although its resources have been up to date for more than two polling
+// intervals since the Application was created, by which time Argo CD would have pruned it
+// once its pull request was closed. It reports nothing for a nil previewPruning.
+func (p *previewPruning) stale(appSet *unstructured.Unstructured, app *unstructured.Unstructured, now time.Time) []*v1.ErrorDetail {
+	if p == nil || p.desired[app.GetName()] {
+		return nil
+	}
+
+	// An Application created after the last transition has been missing at most since its creation
+	since := p.reconciled
+	if created := app.GetCreationTimestamp().Time; created.After(since) {
+		since = created
+	}
+	missing := now.Sub(since)
+	if missing <= 2*p.requeue {
+		return nil
+	}
+	reason := ""
+	if policy, _, _ := unstructured.NestedString(appSet.Object, "spec", "syncPolicy", "applicationsSync"); policy == "create-only" || policy == "create-update" {
+		reason = "; applicationsSync " + policy + " prevents its deletion"
+	}
+	return []*v1.ErrorDetail{finding(rules.StalePreviewApplication, "Application %s/%s is no longer generated by the Pull Request generator of ApplicationSet %s/%s but still exists %s after its resources were last reconciled (pull requests are polled every %s)%s",
+		app.GetNamespace(), app.GetName(), appSet.GetNamespace(), appSet.GetName(), missing.Round(time.Minute), p.requeue, reason)}
+}
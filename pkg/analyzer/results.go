@@ -0,0 +1,104 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/config"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+)
+
+const (
+	// resultKind is the kind of object the analyzer reports on, used by k8sgpt to group findings
+	resultKind = "ApplicationSet"
+	// aggregateKind is the kind of results that cover several ApplicationSets, or none,
+	// and therefore have no parent object
+	aggregateKind = "ApplicationSetList"
+	// resultName names the result of an analysis covering several ApplicationSets
+	resultName = "applicationset-analyzer"
+)
+
+// parentObject identifies the ApplicationSet that owns a set of findings
+func parentObject(namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s", resultKind, namespace, name)
+}
+
+// mergeResults combines the per-ApplicationSet results, which must be sorted, into the
+// analysis result laid out according to the configured result mode. A result covering a
+// single ApplicationSet has its kind and names it as the parent object; other results
+// are of aggregateKind.
+func (a *Handler) mergeResults(summary string, errors []*v1.ErrorDetail, results []appSetResult) *v1.Result {
+	details := []string{summary}
+	suppressed := 0
+	clean := 0
+	for _, result := range results {
+		errors = append(errors, result.errors...)
+		suppressed += result.suppressed
+		switch {
+		case a.config.ResultMode != config.ResultModePerApplicationSet:
+			details = append(details, fmt.Sprintf("ApplicationSet: %s/%s", result.namespace, result.name))
+			details = append(details, result.details...)
+		case len(result.errors) == 0:
+			// Keep the sections focused on the ApplicationSets that need attention
+			clean++
+		default:
+			details = append(details, fmt.Sprintf("=== %s (%d finding(s)) ===", parentObject(result.namespace, result.name), len(result.errors)))
+			details = append(details, result.details...)
+			details = append(details, "  Findings:")
+			for _, e := range result.errors {
+				details = append(details, "    "+e.Text)
+			}
+		}
+	}
+	if clean > 0 {
+		details = append(details, fmt.Sprintf("%d ApplicationSet(s) without findings", clean))
+	}
+	if suppressed > 0 {
+		details = append(details, fmt.Sprintf("Suppressed %d finding(s) by ignore annotations and configured suppressions", suppressed))
+	}
+
+	merged := &v1.Result{
+		Kind:    aggregateKind,
+		Name:    resultName,
+		Details: strings.Join(details, "\n"),
+		Error:   errors,
+	}
+	if len(results) == 1 {
+		merged.Kind = resultKind
+		merged.ParentObject = parentObject(results[0].namespace, results[0].name)
+	}
+	return merged
+}
+
+// references lists the documentation, inspected field and field description of every
+// rule reported in findings, in rule order, so explanations can be grounded in the docs
+func references(findings []*v1.ErrorDetail) []string {
+	reported := make(map[string]bool)
+	for _, f := range findings {
+		if id, _, _, ok := rules.Parse(f.Text); ok {
+			reported[id] = true
+		}
+	}
+
+	var lines []string
+	for _, rule := range rules.Catalog() {
+		if !reported[rule.ID] || rule.Doc == "" {
+			continue
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "References:")
+		}
+		if rule.Field != "" {
+			lines = append(lines, fmt.Sprintf("  %s %s: %s", rule.ID, rule.Field, rule.Doc))
+		} else {
+			lines = append(lines, fmt.Sprintf("  %s: %s", rule.ID, rule.Doc))
+		}
+		for _, line := range strings.Split(rule.Explain, "\n") {
+			if line != "" {
+				lines = append(lines, "    "+line)
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/metrics"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// maxRetryBackoff caps the delay between retries of a Kubernetes API call
+const maxRetryBackoff = 30 * time.Second
+
+// callBudget limits the number of Kubernetes API calls made by a single run
+type callBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+type callBudgetKey struct{}
+
+// withCallBudget returns a copy of ctx carrying a budget of limit API calls; 0 means no limit
+func withCallBudget(ctx context.Context, limit int) context.Context {
+	if limit <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, callBudgetKey{}, &callBudget{limit: int64(limit)})
+}
+
+// spendCall charges one API call against the budget in ctx, if any
+func spendCall(ctx context.Context) error {
+	budget, ok := ctx.Value(callBudgetKey{}).(*callBudget)
+	if !ok {
+		return nil
+	}
+	if budget.used.Add(1) > budget.limit {
+		return fmt.Errorf("API call budget of %d calls per run exhausted", budget.limit)
+	}
+	return nil
+}
+
+// callWithRetry calls fn, charging every attempt against the run's call budget and
+// retrying transient errors with exponential backoff up to the configured number of retries
+func (a *Handler) callWithRetry(ctx context.Context, gvr schema.GroupVersionResource, verb string, fn func() error) error {
+	backoff := a.config.API.RetryBackoff.Duration
+	for attempt := 0; ; attempt++ {
+		if err := spendCall(ctx); err != nil {
+			return err
+		}
+		err := fn()
+		metrics.ObserveAPICall(gvr, verb, err)
+		if err == nil || !isTransient(err) || attempt >= a.config.API.MaxRetries {
+			return err
+		}
+
+		delay := wait.Jitter(backoff, 0.2)
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && time.Duration(seconds)*time.Second > delay {
+			delay = time.Duration(seconds) * time.Second
+		}
+		if delay > maxRetryBackoff {
+			delay = maxRetryBackoff
+		}
+		logging.FromContext(ctx).Debug("Retrying Kubernetes API call after transient error",
+			"resource", gvr.Resource, "verb", verb, "attempt", attempt+1, "delay", delay, "error", err)
+		metrics.ObserveAPIRetry(gvr, verb)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// isTransient reports whether a failed API call may succeed when retried:
+// throttling, server errors, timeouts and dropped connections
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	switch {
+	case apierrors.IsTooManyRequests(err),
+		apierrors.IsServerTimeout(err),
+		apierrors.IsTimeout(err),
+		apierrors.IsInternalError(err),
+		apierrors.IsServiceUnavailable(err),
+		apierrors.IsUnexpectedServerError(err):
+		return true
+	}
+	if status, ok := err.(apierrors.APIStatus); ok && status.Status().Code >= 500 {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err)
+}
@@ -0,0 +1,224 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/logging"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+var secretGVR = corev1.SchemeGroupVersion.WithResource("secrets")
+
+// generatorProvider describes the fields Argo CD needs for one provider of the SCM
+// Provider or Pull Request generator
+type generatorProvider struct {
+	// name is the key of the provider block in the generator
+	name string
+	// required lists the fields that must be set
+	required []string
+	// secretRefs lists the paths of the secret references in the provider block
+	secretRefs [][]string
+	// argocdSecretRefs lists the paths of the secret names in the provider block that
+	// refer to secrets in the Argo CD namespace, such as GitHub App repo-creds
+	argocdSecretRefs [][]string
+	// cloneProtocols lists the supported cloneProtocol values of SCM providers
+	cloneProtocols []string
+}
+
+// scmProviders lists the providers supported by the SCM Provider generator
+var scmProviders = []generatorProvider{
+	{name: "github", required: []string{"organization"}, secretRefs: [][]string{{"tokenRef"}}, argocdSecretRefs: [][]string{{"appSecretName"}}, cloneProtocols: []string{"ssh", "https"}},
+	{name: "gitlab", required: []string{"group"}, secretRefs: [][]string{{"tokenRef"}}, cloneProtocols: []string{"ssh", "https"}},
+	{name: "bitbucketServer", required: []string{"project", "api"}, secretRefs: [][]string{{"basicAuth", "passwordRef"}, {"bearerToken", "tokenRef"}}, cloneProtocols: []string{"ssh", "https"}},
+	{name: "bitbucket", required: []string{"owner", "user"}, secretRefs: [][]string{{"appPasswordRef"}, {"bearerToken", "tokenRef"}}, cloneProtocols: []string{"ssh", "https"}},
+	{name: "gitea", required: []string{"owner", "api"}, secretRefs: [][]string{{"tokenRef"}}, cloneProtocols: []string{"ssh", "https"}},
+	{name: "azureDevOps", required: []string{"organization", "teamProject"}, secretRefs: [][]string{{"accessTokenRef"}}, cloneProtocols: []string{"ssh", "https"}},
+	{name: "awsCodeCommit", cloneProtocols: []string{"ssh", "https", "https-fips"}},
+}
+
+// validateSCMProviderGenerator checks that an SCM Provider generator configures exactly
+// one provider with its required fields, that the secrets it references exist and have
+// the referenced keys, that its filters compile and that its cloneProtocol fits the provider
+func (a *Handler) validateSCMProviderGenerator(ctx context.Context, appSet *unstructured.Unstructured, scmGen interface{}, location string) []*v1.ErrorDetail {
+	scm, ok := scmGen.(map[string]interface{})
+	if !ok {
+		return []*v1.ErrorDetail{finding(rules.InvalidGenerator, "ApplicationSet %s/%s has invalid generator at %s",
+			appSet.GetNamespace(), appSet.GetName(), location)}
+	}
+
+	provider, errors := configuredProvider(appSet, "SCM Provider", location, scm, scmProviders, rules.SCMProviderNotConfigured)
+	if provider == nil {
+		return errors
+	}
+	spec, _ := scm[provider.name].(map[string]interface{})
+	errors = append(errors, a.checkProvider(ctx, appSet, "SCM Provider", location, provider, spec, rules.SCMProviderMissingField)...)
+
+	filters, _ := scm["filters"].([]interface{})
+	for i, f := range filters {
+		filter, _ := f.(map[string]interface{})
+		errors = append(errors, checkFilterPatterns(appSet, "SCM Provider", location, i, filter, "repositoryMatch", "labelMatch", "branchMatch")...)
+		for _, field := range []string{"pathsExist", "pathsDoNotExist"} {
+			paths, _ := filter[field].([]interface{})
+			for _, p := range paths {
+				if s, _ := p.(string); s == "" {
+					errors = append(errors, finding(rules.InvalidGeneratorFilter, "ApplicationSet %s/%s SCM Provider generator at %s filter %d has an empty path in %s",
+						appSet.GetNamespace(), appSet.GetName(), location, i, field))
+				}
+			}
+		}
+	}
+
+	if protocol, _ := scm["cloneProtocol"].(string); protocol != "" {
+		supported := false
+		for _, p := range provider.cloneProtocols {
+			supported = supported || p == protocol
+		}
+		api, _ := spec["api"].(string)
+		switch {
+		case !supported:
+			errors = append(errors, finding(rules.SCMProviderCloneProtocolMismatch, "ApplicationSet %s/%s SCM Provider generator at %s has cloneProtocol %q, which %s does not support (supported: %s)",
+				appSet.GetNamespace(), appSet.GetName(), location, protocol, provider.name, strings.Join(provider.cloneProtocols, ", ")))
+		case strings.HasPrefix(protocol, "https") && strings.HasPrefix(api, "http://"):
+			errors = append(errors, finding(rules.SCMProviderCloneProtocolMismatch, "ApplicationSet %s/%s SCM Provider generator at %s clones over %s but its API %s is served over plain http",
+				appSet.GetNamespace(), appSet.GetName(), location, protocol, api))
+		}
+	}
+
+	return errors
+}
+
+// configuredProvider returns the single provider configured in a generator spec, or nil
+// and a finding of notConfigured if there is none or more than one
+func configuredProvider(appSet *unstructured.Unstructured, kind, location string, generator map[string]interface{}, providers []generatorProvider, notConfigured rules.Rule) (*generatorProvider, []*v1.ErrorDetail) {
+	var configured []generatorProvider
+	for _, provider := range providers {
+		if _, found := generator[provider.name]; found {
+			configured = append(configured, provider)
+		}
+	}
+	if len(configured) == 0 {
+		return nil, []*v1.ErrorDetail{finding(notConfigured, "ApplicationSet %s/%s %s generator at %s has no provider configured",
+			appSet.GetNamespace(), appSet.GetName(), kind, location)}
+	}
+	if len(configured) > 1 {
+		var names []string
+		for _, provider := range configured {
+			names = append(names, provider.name)
+		}
+		return nil, []*v1.ErrorDetail{finding(notConfigured, "ApplicationSet %s/%s %s generator at %s configures several providers (%s); exactly one is required",
+			appSet.GetNamespace(), appSet.GetName(), kind, location, strings.Join(names, ", "))}
+	}
+	return &configured[0], nil
+}
+
+// checkProvider reports the required fields missing from a provider block, as findings
+// of missingField, and the secret references of the block that cannot be resolved in the
+// ApplicationSet's namespace or, for argocdSecretRefs, in the Argo CD namespace
+func (a *Handler) checkProvider(ctx context.Context, appSet *unstructured.Unstructured, kind, location string, provider *generatorProvider, spec map[string]interface{}, missingField rules.Rule) []*v1.ErrorDetail {
+	var errors []*v1.ErrorDetail
+	for _, field := range provider.required {
+		if value, _ := spec[field].(string); value == "" {
+			errors = append(errors, finding(missingField, "ApplicationSet %s/%s %s generator at %s has no %s.%s",
+				appSet.GetNamespace(), appSet.GetName(), kind, location, provider.name, field))
+		}
+	}
+
+	for _, path := range provider.secretRefs {
+		if ref, found, _ := unstructured.NestedFieldNoCopy(spec, path...); found {
+			field := provider.name + "." + strings.Join(path, ".")
+			errors = append(errors, a.checkSecretRef(ctx, appSet, kind, location, field, ref)...)
+		}
+	}
+	for _, path := range provider.argocdSecretRefs {
+		if name, _, _ := unstructured.NestedString(spec, path...); name != "" {
+			field := provider.name + "." + strings.Join(path, ".")
+			errors = append(errors, a.checkSecret(ctx, appSet, kind, location, field, a.config.ArgoCDNamespace, name, "")...)
+		}
+	}
+	return errors
+}
+
+// checkFilterPatterns reports the fields of a generator filter that are not valid regular expressions
+func checkFilterPatterns(appSet *unstructured.Unstructured, kind, location string, index int, filter map[string]interface{}, fields ...string) []*v1.ErrorDetail {
+	var errors []*v1.ErrorDetail
+	for _, field := range fields {
+		pattern, _ := filter[field].(string)
+		if pattern == "" {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			errors = append(errors, finding(rules.InvalidGeneratorFilter, "ApplicationSet %s/%s %s generator at %s filter %d has invalid %s %q: %v",
+				appSet.GetNamespace(), appSet.GetName(), kind, location, index, field, pattern, err))
+		}
+	}
+	return errors
+}
+
+// checkSecretRef verifies that a secret reference of a generator, either a
+// {secretName, key} object or a plain secret name, points to an existing secret in
+// the ApplicationSet's namespace that has the key. If the secret cannot be read the
+// reference is not checked, which is reported when permissions are missing.
+func (a *Handler) checkSecretRef(ctx context.Context, appSet *unstructured.Unstructured, kind, location, field string, ref interface{}) []*v1.ErrorDetail {
+	var name, key string
+	switch r := ref.(type) {
+	case string:
+		name = r
+	case map[string]interface{}:
+		name, _ = r["secretName"].(string)
+		key, _ = r["key"].(string)
+	}
+	if name == "" {
+		return []*v1.ErrorDetail{finding(rules.GeneratorSecretMissing, "ApplicationSet %s/%s %s generator at %s has %s without a secret name",
+			appSet.GetNamespace(), appSet.GetName(), kind, location, field)}
+	}
+	return a.checkSecret(ctx, appSet, kind, location, field, appSet.GetNamespace(), name, key)
+}
+
+// checkSecret verifies that the secret namespace/name referenced by field of a generator
+// exists and, if key is set, has the key. The secret's namespace and name are marked
+// sensitive, since they may come from a ConfigMap rather than the ApplicationSet.
+func (a *Handler) checkSecret(ctx context.Context, appSet *unstructured.Unstructured, kind, location, field, namespace, name, key string) []*v1.ErrorDetail {
+	errors := a.secretFindings(ctx, appSet, kind, location, field, namespace, name, key)
+	markSensitive(errors, []string{namespace, name})
+	return errors
+}
+
+// secretFindings reports the secret namespace/name referenced by field of a generator
+// if it does not exist or, if key is set, does not have the key
+func (a *Handler) secretFindings(ctx context.Context, appSet *unstructured.Unstructured, kind, location, field, namespace, name, key string) []*v1.ErrorDetail {
+	var secret *unstructured.Unstructured
+	err := a.callWithRetry(ctx, secretGVR, "get", func() error {
+		var err error
+		secret, err = a.dynamicClient.Resource(secretGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return []*v1.ErrorDetail{finding(rules.GeneratorSecretMissing, "ApplicationSet %s/%s %s generator at %s: %s references secret %s/%s, which does not exist",
+			appSet.GetNamespace(), appSet.GetName(), kind, location, field, namespace, name)}
+	}
+	if err != nil {
+		logging.FromContext(ctx).Debug("Failed to get secret referenced by generator, skipping check", "secret", name, "namespace", namespace, "error", err)
+		return referenceCheck(appSet, kind, location, fmt.Sprintf("secret %s/%s", namespace, name), "get secrets "+namespaceScope(namespace), err)
+	}
+
+	// Only the key names are inspected; secret values are never read into findings
+	if key != "" {
+		data, _ := secret.Object["data"].(map[string]interface{})
+		stringData, _ := secret.Object["stringData"].(map[string]interface{})
+		_, inData := data[key]
+		_, inStringData := stringData[key]
+		if !inData && !inStringData {
+			return []*v1.ErrorDetail{finding(rules.GeneratorSecretKeyMissing, "ApplicationSet %s/%s %s generator at %s: %s references key %q, which secret %s/%s does not have",
+				appSet.GetNamespace(), appSet.GetName(), kind, location, field, key, namespace, name)}
+		}
+	}
+	return nil
+}
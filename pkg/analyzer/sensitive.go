@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"regexp"
+	"sort"
+	"strings"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// sensitiveKeys are the fields of ApplicationSet and Application specs that hold
+// repository URLs, cluster endpoints, SCM organizations and the names of secrets,
+// ConfigMaps and duck-typed resources. Keys qualified with their parent key, such as
+// "configMapRef.name", only match below that parent.
+var sensitiveKeys = map[string]bool{
+	"repoURL":                      true,
+	"server":                       true,
+	"url":                          true,
+	"api":                          true,
+	"organization":                 true,
+	"group":                        true,
+	"owner":                        true,
+	"repo":                         true,
+	"teamProject":                  true,
+	"secretName":                   true,
+	"appSecretName":                true,
+	"configMapRef":                 true,
+	"configMapRef.name":            true,
+	"clusterDecisionResource.name": true,
+}
+
+// urlPattern finds URLs and scp-style Git remotes in free-form messages
+var urlPattern = regexp.MustCompile(`(?:[a-zA-Z][a-zA-Z0-9+.-]*://|git@)[^\s,;'"()<>\[\]]+`)
+
+// sensitiveValues returns the names, repository URLs and cluster endpoints of an
+// ApplicationSet and its generated Applications that must not leave the cluster
+func sensitiveValues(appSet *unstructured.Unstructured, apps *applicationIndex) []string {
+	values := []string{appSet.GetNamespace(), appSet.GetName()}
+	values = append(values, nestedSensitiveValues("spec", appSet.Object["spec"])...)
+
+	appStatus, _, _ := unstructured.NestedSlice(appSet.Object, "status", "applicationStatus")
+	for _, app := range appStatus {
+		if appInfo, ok := app.(map[string]interface{}); ok {
+			if name, ok := appInfo["application"].(string); ok {
+				values = append(values, name)
+			}
+		}
+	}
+
+	if apps != nil && apps.err == nil {
+		for _, app := range apps.forApplicationSet(appSet) {
+			values = append(values, app.GetNamespace(), app.GetName())
+			values = append(values, nestedSensitiveValues("spec", app.Object["spec"])...)
+		}
+	}
+	return values
+}
+
+// nestedSensitiveValues collects the string values of sensitiveKeys anywhere below obj,
+// the value of the field parent
+func nestedSensitiveValues(parent string, obj interface{}) []string {
+	var values []string
+	switch o := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range o {
+			if s, ok := value.(string); ok && (sensitiveKeys[key] || sensitiveKeys[parent+"."+key]) {
+				values = append(values, s)
+				continue
+			}
+			values = append(values, nestedSensitiveValues(key, value)...)
+		}
+	case []interface{}:
+		for _, value := range o {
+			values = append(values, nestedSensitiveValues(parent, value)...)
+		}
+	}
+	return values
+}
+
+// markSensitive records, for every finding, the given values and any URLs that occur in
+// its text as unmasked/masked pairs, so that k8sgpt can anonymize the finding before it
+// is sent to an AI backend. Longer values come first, so a URL is masked as a whole
+// before the names it contains.
+func markSensitive(findings []*v1.ErrorDetail, values []string) {
+	for _, f := range findings {
+		seen := make(map[string]bool)
+		for _, s := range f.Sensitive {
+			seen[s.Unmasked] = true
+		}
+		for _, value := range append(urlPattern.FindAllString(f.Text, -1), values...) {
+			value = strings.TrimRight(value, ".:")
+			if value == "" || seen[value] || !strings.Contains(f.Text, value) {
+				continue
+			}
+			seen[value] = true
+			f.Sensitive = append(f.Sensitive, &v1.SensitiveData{Unmasked: value, Masked: maskValue(value)})
+		}
+		sort.SliceStable(f.Sensitive, func(i, j int) bool {
+			return len(f.Sensitive[i].Unmasked) > len(f.Sensitive[j].Unmasked)
+		})
+	}
+}
+
+// maskValue derives a stable placeholder for value. Placeholders are upper case, so they
+// never contain a Kubernetes object name, which is always lower case.
+func maskValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	masked := make([]byte, 12)
+	for i := range masked {
+		masked[i] = 'A' + sum[i]%26
+	}
+	return string(masked)
+}
@@ -0,0 +1,101 @@
+package analyzer
+
+import (
+	"sync"
+
+	"github.com/ranakan19/custom-analyzer/pkg/metrics"
+)
+
+// runStats collects per-run counters that are published as metrics when a run completes
+type runStats struct {
+	mu sync.Mutex
+	// cluster is the kubeconfig context the findings are recorded for
+	cluster string
+	// clusters are the kubeconfig contexts whose counters are included
+	clusters        []string
+	applicationSets int
+	applications    int
+	findings        map[metrics.FindingsKey]int
+	// incomplete is set when a check was skipped because a resource it needs could not be read
+	incomplete bool
+}
+
+func newRunStats(cluster string) *runStats {
+	return &runStats{cluster: cluster, clusters: []string{cluster}, findings: make(map[metrics.FindingsKey]int)}
+}
+
+// newClusterSetStats returns empty counters that collect those of several clusters with add
+func newClusterSetStats() *runStats {
+	return &runStats{findings: make(map[metrics.FindingsKey]int)}
+}
+
+// addApplicationSets records analyzed ApplicationSets
+func (s *runStats) addApplicationSets(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applicationSets += count
+}
+
+// addApplications records analyzed generated Applications
+func (s *runStats) addApplications(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applications += count
+}
+
+// addFindings records findings reported by a check for an ApplicationSet namespace
+func (s *runStats) addFindings(check, namespace string, count int) {
+	if count == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.findings[metrics.FindingsKey{Cluster: s.cluster, Check: check, Namespace: namespace}] += count
+}
+
+// markIncomplete records that a check was skipped because a resource it needs could not be read
+func (s *runStats) markIncomplete() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.incomplete = true
+}
+
+// isIncomplete reports whether any check of the run was skipped
+func (s *runStats) isIncomplete() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.incomplete
+}
+
+// add records the counters of another run, such as the analysis of one of several clusters
+func (s *runStats) add(other *runStats) {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applicationSets += other.applicationSets
+	s.applications += other.applications
+	s.clusters = append(s.clusters, other.clusters...)
+	s.incomplete = s.incomplete || other.incomplete
+	for key, count := range other.findings {
+		s.findings[key] += count
+	}
+}
+
+// total returns the number of findings recorded so far
+func (s *runStats) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	total := 0
+	for _, count := range s.findings {
+		total += count
+	}
+	return total
+}
+
+// publish exports the collected counters as metrics
+func (s *runStats) publish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	metrics.SetRunStats(s.clusters, s.applicationSets, s.applications, s.findings)
+}
@@ -0,0 +1,95 @@
+package analyzer
+
+import (
+	"strings"
+	"time"
+
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/ranakan19/custom-analyzer/pkg/config"
+	"github.com/ranakan19/custom-analyzer/pkg/rules"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// ignoreAnnotation lists the comma-separated rule IDs, or "*" for every rule, whose
+// findings are suppressed for the annotated ApplicationSet or Application
+const ignoreAnnotation = "analyzer.k8sgpt.ai/ignore"
+
+// suppressor drops the suppressed findings of one ApplicationSet and counts them
+type suppressor struct {
+	namespace    string
+	name         string
+	ignored      map[string]bool
+	suppressions []config.Suppression
+	suppressed   int
+}
+
+// newSuppressor collects the ignore annotation of appSet and the config suppressions
+// that have not expired
+func (a *Handler) newSuppressor(appSet *unstructured.Unstructured) *suppressor {
+	s := &suppressor{
+		namespace: appSet.GetNamespace(),
+		name:      appSet.GetName(),
+		ignored:   ignoredRules(appSet),
+	}
+	now := time.Now()
+	for _, suppression := range a.config.Suppressions {
+		if suppression.Active(now) {
+			s.suppressions = append(s.suppressions, suppression)
+		}
+	}
+	return s
+}
+
+// filter removes the findings suppressed for the ApplicationSet and returns the others
+func (s *suppressor) filter(findings []*v1.ErrorDetail) []*v1.ErrorDetail {
+	return s.filterIgnoring(findings, nil)
+}
+
+// filterApplication removes the findings of a generated Application that are suppressed
+// for it or for its ApplicationSet and returns the others
+func (s *suppressor) filterApplication(app *unstructured.Unstructured, findings []*v1.ErrorDetail) []*v1.ErrorDetail {
+	return s.filterIgnoring(findings, ignoredRules(app))
+}
+
+func (s *suppressor) filterIgnoring(findings []*v1.ErrorDetail, ignored map[string]bool) []*v1.ErrorDetail {
+	var kept []*v1.ErrorDetail
+	for _, f := range findings {
+		if s.suppresses(f, ignored) {
+			s.suppressed++
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+func (s *suppressor) suppresses(f *v1.ErrorDetail, ignored map[string]bool) bool {
+	id, _, _, ok := rules.Parse(f.Text)
+	if !ok {
+		return false
+	}
+	if s.ignored["*"] || s.ignored[id] || ignored["*"] || ignored[id] {
+		return true
+	}
+	for _, suppression := range s.suppressions {
+		if suppression.Matches(id, s.namespace, s.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ignoredRules returns the rule IDs listed in the ignore annotation of obj
+func ignoredRules(obj *unstructured.Unstructured) map[string]bool {
+	value, ok := obj.GetAnnotations()[ignoreAnnotation]
+	if !ok {
+		return nil
+	}
+	ignored := make(map[string]bool)
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.ToUpper(strings.TrimSpace(id)); id != "" {
+			ignored[id] = true
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,656 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+// EnvPrefix is the prefix for all environment variables read by the analyzer
+const EnvPrefix = "APPSET_ANALYZER_"
+
+// Names of the checks that can be enabled or disabled
+const (
+	CheckConditions   = "conditions"
+	CheckProgressing  = "progressing"
+	CheckGenerators   = "generators"
+	CheckApplications = "applications"
+)
+
+// Result modes controlling how findings are laid out in the analysis result
+const (
+	// ResultModeCombined reports every ApplicationSet in one list of findings and details
+	ResultModeCombined = "combined"
+	// ResultModePerApplicationSet groups findings and details into one section per ApplicationSet
+	ResultModePerApplicationSet = "per-applicationset"
+)
+
+// AllChecks lists every check known to the analyzer, in execution order
+var AllChecks = []string{
+	CheckConditions,
+	CheckProgressing,
+	CheckGenerators,
+	CheckApplications,
+}
+
+// Config holds the settings for the analyzer server and its checks
+type Config struct {
+	// ListenAddress is the address the gRPC server listens on
+	ListenAddress string `json:"listenAddress"`
+	// TLS configures transport security for the gRPC server
+	TLS TLS `json:"tls"`
+	// Log configures the analyzer's structured logging
+	Log Log `json:"log"`
+	// MetricsAddress is the address of the Prometheus metrics HTTP listener; empty disables it
+	MetricsAddress string `json:"metricsAddress,omitempty"`
+	// ShutdownTimeout is how long in-flight RPCs may drain after SIGTERM/SIGINT
+	ShutdownTimeout metav1.Duration `json:"shutdownTimeout"`
+	// HealthCheckInterval is how often Kubernetes API reachability is checked for the health service
+	HealthCheckInterval metav1.Duration `json:"healthCheckInterval"`
+	// Kubeconfig is the path to a kubeconfig file; empty means in-cluster or default loading rules
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// KubeContext is the kubeconfig context to use; empty means the current context
+	KubeContext string `json:"kubeContext,omitempty"`
+	// KubeContexts lists kubeconfig contexts of several clusters to analyze in a single run;
+	// when set it takes the place of KubeContext
+	KubeContexts []string `json:"kubeContexts,omitempty"`
+	// API tunes rate limiting, retries and the call budget of Kubernetes API calls
+	API API `json:"api"`
+	// Namespaces restricts analysis to the given namespaces; empty means all namespaces
+	Namespaces []string `json:"namespaces,omitempty"`
+	// LabelSelector restricts analysis to ApplicationSets matching this label selector
+	LabelSelector string `json:"labelSelector,omitempty"`
+	// FieldSelector restricts analysis to ApplicationSets matching this field selector
+	FieldSelector string `json:"fieldSelector,omitempty"`
+	// ApplicationLabelSelector restricts the generated Applications that are analyzed
+	ApplicationLabelSelector string `json:"applicationLabelSelector,omitempty"`
+	// ApplicationFieldSelector restricts the generated Applications that are analyzed
+	ApplicationFieldSelector string `json:"applicationFieldSelector,omitempty"`
+	// PageSize is the maximum number of objects fetched per List call; 0 disables pagination
+	PageSize int64 `json:"pageSize"`
+	// Parallelism is the maximum number of ApplicationSets analyzed concurrently
+	Parallelism int `json:"parallelism"`
+	// ApplicationSetTimeout bounds the analysis of a single ApplicationSet; 0 means no limit
+	ApplicationSetTimeout metav1.Duration `json:"applicationSetTimeout"`
+	// Watch keeps ApplicationSets and Applications in an informer cache and serves Run from it
+	Watch bool `json:"watch,omitempty"`
+	// ResyncPeriod is how often the informer cache is resynced in watch mode; 0 disables resyncs
+	ResyncPeriod metav1.Duration `json:"resyncPeriod"`
+	// ResultMode is ResultModeCombined or ResultModePerApplicationSet
+	ResultMode string `json:"resultMode"`
+	// ArgoCDNamespace is the namespace of the Argo CD installation, where generator
+	// ConfigMaps and the secrets of plugin generators are looked up
+	ArgoCDNamespace string `json:"argocdNamespace"`
+	// Checks configures which checks run and their thresholds
+	Checks Checks `json:"checks"`
+	// Suppressions silence findings of matching ApplicationSets; they can only be set in the config file
+	Suppressions []Suppression `json:"suppressions,omitempty"`
+	// ListRules prints the rule catalog and exits instead of serving; it can only be set by flag
+	ListRules bool `json:"-"`
+}
+
+// Suppression silences findings of the given rules for ApplicationSets matching the
+// namespace and name globs, and for the Applications they generate
+type Suppression struct {
+	// Rules lists the rule IDs to suppress, or "*" for every rule
+	Rules []string `json:"rules"`
+	// Namespace is a glob matched against the ApplicationSet namespace; empty matches every namespace
+	Namespace string `json:"namespace,omitempty"`
+	// Name is a glob matched against the ApplicationSet name; empty matches every name
+	Name string `json:"name,omitempty"`
+	// Expires is the date (2006-01-02) or time (RFC 3339) from which the suppression no longer applies
+	Expires string `json:"expires,omitempty"`
+	// Reason documents why the findings are suppressed
+	Reason string `json:"reason,omitempty"`
+}
+
+// ExpiresAt returns the parsed expiry, or the zero time if the suppression never expires
+func (s Suppression) ExpiresAt() (time.Time, error) {
+	if s.Expires == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s.Expires); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s.Expires)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid expiry %q (must be a date like 2006-01-02 or an RFC 3339 time)", s.Expires)
+	}
+	return t, nil
+}
+
+// Active reports whether the suppression has not expired at now
+func (s Suppression) Active(now time.Time) bool {
+	expires, err := s.ExpiresAt()
+	return err == nil && (expires.IsZero() || now.Before(expires))
+}
+
+// Matches reports whether the suppression covers a finding of ruleID for the ApplicationSet namespace/name
+func (s Suppression) Matches(ruleID, namespace, name string) bool {
+	if !globMatch(s.Namespace, namespace) || !globMatch(s.Name, name) {
+		return false
+	}
+	for _, rule := range s.Rules {
+		if rule == "*" || strings.EqualFold(rule, ruleID) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatch matches value against a path.Match pattern; an empty pattern matches everything
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
+
+// Log configures structured logging
+type Log struct {
+	// Level is the minimum level to log: debug, info, warn or error
+	Level string `json:"level"`
+	// Format is the output format: text or json
+	Format string `json:"format"`
+}
+
+// TLS configures server TLS and, with a client CA, mutual TLS
+type TLS struct {
+	// CertFile is the path to the PEM-encoded server certificate
+	CertFile string `json:"certFile,omitempty"`
+	// KeyFile is the path to the PEM-encoded server private key
+	KeyFile string `json:"keyFile,omitempty"`
+	// ClientCAFile is the path to a PEM bundle used to verify client certificates;
+	// when set, clients must present a certificate signed by one of these CAs
+	ClientCAFile string `json:"clientCAFile,omitempty"`
+}
+
+// Enabled reports whether the server should serve TLS
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// API tunes how the analyzer calls the Kubernetes API
+type API struct {
+	// QPS is the sustained rate of requests per second allowed by the client-side rate limiter
+	QPS float32 `json:"qps"`
+	// Burst is the number of requests allowed above QPS for short periods
+	Burst int `json:"burst"`
+	// MaxRetries is how often a call failing with a transient error (429, 5xx, timeout) is retried
+	MaxRetries int `json:"maxRetries"`
+	// RetryBackoff is the delay before the first retry; it doubles with every further retry
+	RetryBackoff metav1.Duration `json:"retryBackoff"`
+	// CallBudget caps the number of API calls, including retries, made by a single run; 0 means no limit
+	CallBudget int `json:"callBudget,omitempty"`
+}
+
+// Checks configures the enabled checks and their thresholds
+type Checks struct {
+	// Enabled lists the checks to run; empty means all checks
+	Enabled []string `json:"enabled,omitempty"`
+	// Thresholds tunes when a check reports a finding
+	Thresholds Thresholds `json:"thresholds"`
+}
+
+// Thresholds tunes when checks report findings
+type Thresholds struct {
+	// ProgressingTimeout is how long an ApplicationSet may stay Progressing before it is reported
+	ProgressingTimeout metav1.Duration `json:"progressingTimeout"`
+}
+
+// Default returns the default configuration
+func Default() *Config {
+	return &Config{
+		ListenAddress: ":8085",
+		Log:           Log{Level: "info", Format: "text"},
+		API: API{
+			QPS:          20,
+			Burst:        30,
+			MaxRetries:   3,
+			RetryBackoff: metav1.Duration{Duration: 200 * time.Millisecond},
+		},
+		PageSize:              500,
+		Parallelism:           4,
+		ApplicationSetTimeout: metav1.Duration{Duration: 30 * time.Second},
+		ShutdownTimeout:       metav1.Duration{Duration: 30 * time.Second},
+		HealthCheckInterval:   metav1.Duration{Duration: 10 * time.Second},
+		ResyncPeriod:          metav1.Duration{Duration: 10 * time.Minute},
+		ResultMode:            ResultModeCombined,
+		ArgoCDNamespace:       "argocd",
+	}
+}
+
+// CheckEnabled reports whether the named check should run
+func (c *Config) CheckEnabled(name string) bool {
+	if len(c.Checks.Enabled) == 0 {
+		return true
+	}
+	for _, enabled := range c.Checks.Enabled {
+		if enabled == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the configuration for errors
+func (c *Config) Validate() error {
+	if c.ListenAddress == "" {
+		return fmt.Errorf("listen address must not be empty")
+	}
+	if c.TLS.Enabled() && (c.TLS.CertFile == "" || c.TLS.KeyFile == "") {
+		return fmt.Errorf("both TLS certificate and key files must be set")
+	}
+	if c.TLS.ClientCAFile != "" && !c.TLS.Enabled() {
+		return fmt.Errorf("client CA file requires a TLS certificate and key")
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		return fmt.Errorf("invalid log level %q", c.Log.Level)
+	}
+	if c.Log.Format != "text" && c.Log.Format != "json" {
+		return fmt.Errorf("invalid log format %q (must be text or json)", c.Log.Format)
+	}
+	if c.ShutdownTimeout.Duration <= 0 {
+		return fmt.Errorf("shutdown timeout must be positive")
+	}
+	if c.HealthCheckInterval.Duration <= 0 {
+		return fmt.Errorf("health check interval must be positive")
+	}
+	for _, selector := range []string{c.LabelSelector, c.ApplicationLabelSelector} {
+		if _, err := labels.Parse(selector); err != nil {
+			return fmt.Errorf("invalid label selector %q: %v", selector, err)
+		}
+	}
+	for _, selector := range []string{c.FieldSelector, c.ApplicationFieldSelector} {
+		if _, err := fields.ParseSelector(selector); err != nil {
+			return fmt.Errorf("invalid field selector %q: %v", selector, err)
+		}
+	}
+	if c.PageSize < 0 {
+		return fmt.Errorf("page size must not be negative")
+	}
+	if c.Parallelism < 1 {
+		return fmt.Errorf("parallelism must be at least 1")
+	}
+	if c.ApplicationSetTimeout.Duration < 0 {
+		return fmt.Errorf("ApplicationSet timeout must not be negative")
+	}
+	if c.API.QPS <= 0 {
+		return fmt.Errorf("API QPS must be positive")
+	}
+	if c.API.Burst < 1 {
+		return fmt.Errorf("API burst must be at least 1")
+	}
+	if c.API.MaxRetries < 0 {
+		return fmt.Errorf("API max retries must not be negative")
+	}
+	if c.API.RetryBackoff.Duration <= 0 {
+		return fmt.Errorf("API retry backoff must be positive")
+	}
+	if c.API.CallBudget < 0 {
+		return fmt.Errorf("API call budget must not be negative")
+	}
+	if c.KubeContext != "" && len(c.KubeContexts) > 0 {
+		return fmt.Errorf("kube context and kube contexts are mutually exclusive")
+	}
+	seen := make(map[string]bool)
+	for _, name := range c.KubeContexts {
+		if seen[name] {
+			return fmt.Errorf("duplicate kube context %q", name)
+		}
+		seen[name] = true
+	}
+	if c.ResyncPeriod.Duration < 0 {
+		return fmt.Errorf("resync period must not be negative")
+	}
+	if c.ResultMode != ResultModeCombined && c.ResultMode != ResultModePerApplicationSet {
+		return fmt.Errorf("invalid result mode %q (must be %s or %s)", c.ResultMode, ResultModeCombined, ResultModePerApplicationSet)
+	}
+	if c.ArgoCDNamespace == "" {
+		return fmt.Errorf("Argo CD namespace must not be empty")
+	}
+	for _, name := range c.Checks.Enabled {
+		if !isKnownCheck(name) {
+			return fmt.Errorf("unknown check %q (known checks: %s)", name, strings.Join(AllChecks, ", "))
+		}
+	}
+	if c.Checks.Thresholds.ProgressingTimeout.Duration < 0 {
+		return fmt.Errorf("progressing timeout must not be negative")
+	}
+	for i, suppression := range c.Suppressions {
+		if err := suppression.validate(); err != nil {
+			return fmt.Errorf("suppression %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+var ruleIDPattern = regexp.MustCompile(`^(?i)ASA\d{3}$`)
+
+func (s Suppression) validate() error {
+	if len(s.Rules) == 0 {
+		return fmt.Errorf("at least one rule ID or \"*\" is required")
+	}
+	for _, rule := range s.Rules {
+		if rule != "*" && !ruleIDPattern.MatchString(rule) {
+			return fmt.Errorf("invalid rule ID %q", rule)
+		}
+	}
+	for _, pattern := range []string{s.Namespace, s.Name} {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %v", pattern, err)
+		}
+	}
+	_, err := s.ExpiresAt()
+	return err
+}
+
+// Load builds the configuration from defaults, an optional config file,
+// environment variables and command-line flags, in increasing order of precedence
+func Load(args []string) (*Config, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, error) {
+	cfg := Default()
+
+	configFile, _ := lookupEnv(EnvPrefix + "CONFIG")
+	if path, ok := configPathFromArgs(args); ok {
+		configFile = path
+	}
+	if configFile != "" {
+		if err := cfg.loadFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.applyEnv(lookupEnv); err != nil {
+		return nil, err
+	}
+
+	fs := flag.NewFlagSet("applicationset-analyzer", flag.ContinueOnError)
+	cfg.registerFlags(fs, &configFile)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile merges the YAML config file at path into the configuration
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %v", path, err)
+	}
+	return nil
+}
+
+// applyEnv overrides the configuration with APPSET_ANALYZER_* environment variables
+func (c *Config) applyEnv(lookupEnv func(string) (string, bool)) error {
+	if v, ok := lookupEnv(EnvPrefix + "LISTEN_ADDRESS"); ok {
+		c.ListenAddress = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "TLS_CERT_FILE"); ok {
+		c.TLS.CertFile = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "TLS_KEY_FILE"); ok {
+		c.TLS.KeyFile = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "TLS_CLIENT_CA_FILE"); ok {
+		c.TLS.ClientCAFile = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "LOG_LEVEL"); ok {
+		c.Log.Level = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "LOG_FORMAT"); ok {
+		c.Log.Format = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "METRICS_ADDRESS"); ok {
+		c.MetricsAddress = v
+	}
+	if err := parseDurationEnv(lookupEnv, "SHUTDOWN_TIMEOUT", &c.ShutdownTimeout.Duration); err != nil {
+		return err
+	}
+	if err := parseDurationEnv(lookupEnv, "HEALTH_CHECK_INTERVAL", &c.HealthCheckInterval.Duration); err != nil {
+		return err
+	}
+	if v, ok := lookupEnv(EnvPrefix + "KUBECONFIG"); ok {
+		c.Kubeconfig = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "KUBE_CONTEXT"); ok {
+		c.KubeContext = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "KUBE_API_QPS"); ok {
+		qps, err := strconv.ParseFloat(v, 32)
+		if err != nil {
+			return fmt.Errorf("invalid %sKUBE_API_QPS: %v", EnvPrefix, err)
+		}
+		c.API.QPS = float32(qps)
+	}
+	if err := parseIntEnv(lookupEnv, "KUBE_API_BURST", &c.API.Burst); err != nil {
+		return err
+	}
+	if err := parseIntEnv(lookupEnv, "API_MAX_RETRIES", &c.API.MaxRetries); err != nil {
+		return err
+	}
+	if err := parseDurationEnv(lookupEnv, "API_RETRY_BACKOFF", &c.API.RetryBackoff.Duration); err != nil {
+		return err
+	}
+	if err := parseIntEnv(lookupEnv, "API_CALL_BUDGET", &c.API.CallBudget); err != nil {
+		return err
+	}
+	if v, ok := lookupEnv(EnvPrefix + "KUBE_CONTEXTS"); ok {
+		c.KubeContexts = splitList(v)
+	}
+	if v, ok := lookupEnv(EnvPrefix + "NAMESPACES"); ok {
+		c.Namespaces = splitList(v)
+	}
+	if v, ok := lookupEnv(EnvPrefix + "LABEL_SELECTOR"); ok {
+		c.LabelSelector = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "FIELD_SELECTOR"); ok {
+		c.FieldSelector = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "APPLICATION_LABEL_SELECTOR"); ok {
+		c.ApplicationLabelSelector = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "APPLICATION_FIELD_SELECTOR"); ok {
+		c.ApplicationFieldSelector = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "PAGE_SIZE"); ok {
+		pageSize, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %sPAGE_SIZE: %v", EnvPrefix, err)
+		}
+		c.PageSize = pageSize
+	}
+	if err := parseIntEnv(lookupEnv, "PARALLELISM", &c.Parallelism); err != nil {
+		return err
+	}
+	if err := parseDurationEnv(lookupEnv, "APPLICATIONSET_TIMEOUT", &c.ApplicationSetTimeout.Duration); err != nil {
+		return err
+	}
+	if v, ok := lookupEnv(EnvPrefix + "WATCH"); ok {
+		watch, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid %sWATCH: %v", EnvPrefix, err)
+		}
+		c.Watch = watch
+	}
+	if err := parseDurationEnv(lookupEnv, "RESYNC_PERIOD", &c.ResyncPeriod.Duration); err != nil {
+		return err
+	}
+	if v, ok := lookupEnv(EnvPrefix + "RESULT_MODE"); ok {
+		c.ResultMode = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "ARGOCD_NAMESPACE"); ok {
+		c.ArgoCDNamespace = v
+	}
+	if v, ok := lookupEnv(EnvPrefix + "CHECKS"); ok {
+		c.Checks.Enabled = splitList(v)
+	}
+	if err := parseDurationEnv(lookupEnv, "PROGRESSING_TIMEOUT", &c.Checks.Thresholds.ProgressingTimeout.Duration); err != nil {
+		return err
+	}
+	return nil
+}
+
+// parseIntEnv sets target from the APPSET_ANALYZER_<name> environment variable if present
+func parseIntEnv(lookupEnv func(string) (string, bool), name string, target *int) error {
+	v, ok := lookupEnv(EnvPrefix + name)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s%s: %v", EnvPrefix, name, err)
+	}
+	*target = n
+	return nil
+}
+
+// parseDurationEnv sets target from the APPSET_ANALYZER_<name> environment variable if present
+func parseDurationEnv(lookupEnv func(string) (string, bool), name string, target *time.Duration) error {
+	v, ok := lookupEnv(EnvPrefix + name)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s%s: %v", EnvPrefix, name, err)
+	}
+	*target = d
+	return nil
+}
+
+// registerFlags binds command-line flags to the configuration, using the
+// current values as defaults so that unset flags keep file and env settings
+func (c *Config) registerFlags(fs *flag.FlagSet, configFile *string) {
+	fs.StringVar(configFile, "config", *configFile, "Path to a YAML config file")
+	fs.BoolVar(&c.ListRules, "list-rules", c.ListRules, "Print the catalog of rules reported by the analyzer and exit")
+	fs.StringVar(&c.ListenAddress, "listen-address", c.ListenAddress, "Address the gRPC server listens on")
+	fs.StringVar(&c.TLS.CertFile, "tls-cert-file", c.TLS.CertFile, "Path to the server TLS certificate (enables TLS)")
+	fs.StringVar(&c.TLS.KeyFile, "tls-key-file", c.TLS.KeyFile, "Path to the server TLS private key")
+	fs.StringVar(&c.TLS.ClientCAFile, "tls-client-ca-file", c.TLS.ClientCAFile, "Path to a CA bundle for verifying client certificates (enables mTLS)")
+	fs.StringVar(&c.Log.Level, "log-level", c.Log.Level, "Log level (debug, info, warn, error)")
+	fs.StringVar(&c.Log.Format, "log-format", c.Log.Format, "Log format (text, json)")
+	fs.StringVar(&c.MetricsAddress, "metrics-address", c.MetricsAddress, "Address of the Prometheus metrics listener, e.g. :9090 (disabled if empty)")
+	fs.DurationVar(&c.ShutdownTimeout.Duration, "shutdown-timeout", c.ShutdownTimeout.Duration, "How long in-flight requests may drain on shutdown")
+	fs.DurationVar(&c.HealthCheckInterval.Duration, "health-check-interval", c.HealthCheckInterval.Duration, "How often Kubernetes API reachability is checked")
+	fs.StringVar(&c.Kubeconfig, "kubeconfig", c.Kubeconfig, "Path to a kubeconfig file (defaults to in-cluster config, then ~/.kube/config)")
+	fs.StringVar(&c.KubeContext, "context", c.KubeContext, "Kubeconfig context to use")
+	fs.Var((*float32Value)(&c.API.QPS), "kube-api-qps", "Sustained Kubernetes API requests per second allowed by the client-side rate limiter")
+	fs.IntVar(&c.API.Burst, "kube-api-burst", c.API.Burst, "Kubernetes API requests allowed above the QPS for short periods")
+	fs.IntVar(&c.API.MaxRetries, "api-max-retries", c.API.MaxRetries, "How often a Kubernetes API call failing with a transient error is retried")
+	fs.DurationVar(&c.API.RetryBackoff.Duration, "api-retry-backoff", c.API.RetryBackoff.Duration, "Delay before the first retry of a Kubernetes API call, doubled for every further retry")
+	fs.IntVar(&c.API.CallBudget, "api-call-budget", c.API.CallBudget, "Maximum Kubernetes API calls per analysis, including retries (0 means no limit)")
+	fs.Var((*stringList)(&c.KubeContexts), "contexts", "Comma-separated kubeconfig contexts of clusters to analyze together")
+	fs.Var((*stringList)(&c.Namespaces), "namespaces", "Comma-separated namespaces to analyze (defaults to all namespaces)")
+	fs.StringVar(&c.LabelSelector, "label-selector", c.LabelSelector, "Label selector for the ApplicationSets to analyze")
+	fs.StringVar(&c.FieldSelector, "field-selector", c.FieldSelector, "Field selector for the ApplicationSets to analyze")
+	fs.StringVar(&c.ApplicationLabelSelector, "application-label-selector", c.ApplicationLabelSelector, "Label selector for the generated Applications to analyze")
+	fs.StringVar(&c.ApplicationFieldSelector, "application-field-selector", c.ApplicationFieldSelector, "Field selector for the generated Applications to analyze")
+	fs.Int64Var(&c.PageSize, "page-size", c.PageSize, "Maximum number of objects fetched per List call (0 disables pagination)")
+	fs.IntVar(&c.Parallelism, "parallelism", c.Parallelism, "Maximum number of ApplicationSets analyzed concurrently")
+	fs.DurationVar(&c.ApplicationSetTimeout.Duration, "applicationset-timeout", c.ApplicationSetTimeout.Duration, "Deadline for analyzing a single ApplicationSet (0 disables it)")
+	fs.BoolVar(&c.Watch, "watch", c.Watch, "Serve analyses from an informer cache kept up to date by watches")
+	fs.DurationVar(&c.ResyncPeriod.Duration, "resync-period", c.ResyncPeriod.Duration, "How often the informer cache is resynced in watch mode (0 disables resyncs)")
+	fs.StringVar(&c.ResultMode, "result-mode", c.ResultMode, fmt.Sprintf("Layout of the analysis result: %s or %s", ResultModeCombined, ResultModePerApplicationSet))
+	fs.StringVar(&c.ArgoCDNamespace, "argocd-namespace", c.ArgoCDNamespace, "Namespace of the Argo CD installation, where generator ConfigMaps are looked up")
+	fs.Var((*stringList)(&c.Checks.Enabled), "checks", fmt.Sprintf("Comma-separated checks to run (%s)", strings.Join(AllChecks, ", ")))
+	fs.DurationVar(&c.Checks.Thresholds.ProgressingTimeout.Duration, "progressing-timeout", c.Checks.Thresholds.ProgressingTimeout.Duration,
+		"How long an ApplicationSet may be Progressing before it is reported")
+}
+
+// configPathFromArgs finds the -config flag before the full flag set is parsed
+func configPathFromArgs(args []string) (string, bool) {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if name == "config" && i+1 < len(args) {
+			return args[i+1], true
+		}
+		if strings.HasPrefix(name, "config=") {
+			return strings.TrimPrefix(name, "config="), true
+		}
+	}
+	return "", false
+}
+
+func isKnownCheck(name string) bool {
+	for _, check := range AllChecks {
+		if check == name {
+			return true
+		}
+	}
+	return false
+}
+
+// float32Value is a flag.Value for float32 settings
+type float32Value float32
+
+func (f *float32Value) String() string {
+	if f == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(float64(*f), 'g', -1, 32)
+}
+
+func (f *float32Value) Set(value string) error {
+	v, err := strconv.ParseFloat(value, 32)
+	if err != nil {
+		return err
+	}
+	*f = float32Value(v)
+	return nil
+}
+
+// stringList is a flag.Value for comma-separated lists
+type stringList []string
+
+func (s *stringList) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = splitList(value)
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
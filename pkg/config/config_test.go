@@ -0,0 +1,211 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envFrom(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(nil, envFrom(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, ":8085", cfg.ListenAddress)
+	assert.Empty(t, cfg.Namespaces)
+	assert.Equal(t, int64(500), cfg.PageSize)
+	for _, check := range AllChecks {
+		assert.True(t, cfg.CheckEnabled(check), "check %s should be enabled by default", check)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, `
+listenAddress: ":9000"
+kubeconfig: /etc/kube/config
+kubeContext: file-context
+namespaces: [argocd, team-a]
+checks:
+  enabled: [conditions, generators]
+  thresholds:
+    progressingTimeout: 10m
+`)
+
+	// File only
+	cfg, err := load([]string{"-config", path}, envFrom(nil))
+	require.NoError(t, err)
+	assert.Equal(t, ":9000", cfg.ListenAddress)
+	assert.Equal(t, "/etc/kube/config", cfg.Kubeconfig)
+	assert.Equal(t, "file-context", cfg.KubeContext)
+	assert.Equal(t, []string{"argocd", "team-a"}, cfg.Namespaces)
+	assert.Equal(t, 10*time.Minute, cfg.Checks.Thresholds.ProgressingTimeout.Duration)
+	assert.True(t, cfg.CheckEnabled(CheckGenerators))
+	assert.False(t, cfg.CheckEnabled(CheckApplications))
+
+	// API client tuning
+	cfg, err = load([]string{"-kube-api-qps", "50.5", "-api-call-budget", "100"}, envFrom(map[string]string{EnvPrefix + "KUBE_API_BURST": "80"}))
+	require.NoError(t, err)
+	assert.Equal(t, float32(50.5), cfg.API.QPS)
+	assert.Equal(t, 80, cfg.API.Burst)
+	assert.Equal(t, 100, cfg.API.CallBudget)
+	assert.Equal(t, 3, cfg.API.MaxRetries)
+	assert.Equal(t, ResultModeCombined, cfg.ResultMode)
+	assert.Equal(t, "argocd", cfg.ArgoCDNamespace)
+
+	cfg, err = load(nil, envFrom(map[string]string{EnvPrefix + "ARGOCD_NAMESPACE": "gitops"}))
+	require.NoError(t, err)
+	assert.Equal(t, "gitops", cfg.ArgoCDNamespace)
+
+	// Suppressions can only be set in the config file
+	suppressionsPath := writeConfigFile(t, `
+suppressions:
+- rules: [ASA012, asa013]
+  namespace: team-*
+  expires: "2030-01-01"
+  reason: clusters are filtered by the platform team
+- rules: ["*"]
+  name: legacy-?
+`)
+	cfg, err = load([]string{"-config", suppressionsPath}, envFrom(nil))
+	require.NoError(t, err)
+	require.Len(t, cfg.Suppressions, 2)
+	teams := cfg.Suppressions[0]
+	assert.True(t, teams.Matches("ASA013", "team-a", "guestbook"))
+	assert.False(t, teams.Matches("ASA013", "argocd", "guestbook"))
+	assert.False(t, teams.Matches("ASA001", "team-a", "guestbook"))
+	assert.True(t, teams.Active(time.Date(2029, 12, 31, 23, 0, 0, 0, time.UTC)))
+	assert.False(t, teams.Active(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+	legacy := cfg.Suppressions[1]
+	assert.True(t, legacy.Matches("ASA001", "argocd", "legacy-1"))
+	assert.False(t, legacy.Matches("ASA001", "argocd", "legacy-10"))
+	assert.True(t, legacy.Active(time.Now()))
+
+	// Listing the rules
+	cfg, err = load([]string{"-list-rules"}, envFrom(nil))
+	require.NoError(t, err)
+	assert.True(t, cfg.ListRules)
+
+	// Several contexts
+	cfg, err = load([]string{"-contexts", "mgmt-eu,mgmt-us"}, envFrom(nil))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mgmt-eu", "mgmt-us"}, cfg.KubeContexts)
+
+	// Env overrides file, flags override env
+	env := envFrom(map[string]string{
+		EnvPrefix + "CONFIG":         path,
+		EnvPrefix + "LISTEN_ADDRESS": ":9100",
+		EnvPrefix + "KUBE_CONTEXT":   "env-context",
+		EnvPrefix + "NAMESPACES":     "argocd, team-b",
+	})
+	cfg, err = load([]string{"--context=flag-context", "-progressing-timeout", "1m"}, env)
+	require.NoError(t, err)
+	assert.Equal(t, ":9100", cfg.ListenAddress)
+	assert.Equal(t, "flag-context", cfg.KubeContext)
+	assert.Equal(t, []string{"argocd", "team-b"}, cfg.Namespaces)
+	assert.Equal(t, time.Minute, cfg.Checks.Thresholds.ProgressingTimeout.Duration)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := load([]string{"-checks", "conditions,unknown"}, envFrom(nil))
+	assert.ErrorContains(t, err, `unknown check "unknown"`)
+
+	path := writeConfigFile(t, "listenAddres: typo\n")
+	_, err = load([]string{"-config", path}, envFrom(nil))
+	assert.ErrorContains(t, err, "failed to parse config file")
+
+	_, err = load(nil, envFrom(map[string]string{EnvPrefix + "PROGRESSING_TIMEOUT": "soon"}))
+	assert.ErrorContains(t, err, "PROGRESSING_TIMEOUT")
+
+	_, err = load([]string{"-tls-cert-file", "tls.crt"}, envFrom(nil))
+	assert.ErrorContains(t, err, "both TLS certificate and key files must be set")
+
+	_, err = load([]string{"-tls-client-ca-file", "ca.crt"}, envFrom(nil))
+	assert.ErrorContains(t, err, "client CA file requires a TLS certificate and key")
+
+	_, err = load([]string{"-label-selector", "team in (a"}, envFrom(nil))
+	assert.ErrorContains(t, err, "invalid label selector")
+
+	_, err = load([]string{"-application-field-selector", "metadata.name"}, envFrom(nil))
+	assert.ErrorContains(t, err, "invalid field selector")
+
+	_, err = load(nil, envFrom(map[string]string{EnvPrefix + "PAGE_SIZE": "many"}))
+	assert.ErrorContains(t, err, "PAGE_SIZE")
+
+	_, err = load([]string{"-page-size", "-1"}, envFrom(nil))
+	assert.ErrorContains(t, err, "page size must not be negative")
+
+	_, err = load([]string{"-parallelism", "0"}, envFrom(nil))
+	assert.ErrorContains(t, err, "parallelism must be at least 1")
+
+	_, err = load([]string{"-context", "prod", "-contexts", "prod,staging"}, envFrom(nil))
+	assert.ErrorContains(t, err, "mutually exclusive")
+
+	_, err = load(nil, envFrom(map[string]string{EnvPrefix + "KUBE_CONTEXTS": "prod,prod"}))
+	assert.ErrorContains(t, err, `duplicate kube context "prod"`)
+
+	_, err = load([]string{"-kube-api-qps", "0"}, envFrom(nil))
+	assert.ErrorContains(t, err, "API QPS must be positive")
+
+	_, err = load(nil, envFrom(map[string]string{EnvPrefix + "KUBE_API_BURST": "lots"}))
+	assert.ErrorContains(t, err, "KUBE_API_BURST")
+
+	_, err = load([]string{"-api-max-retries", "-1"}, envFrom(nil))
+	assert.ErrorContains(t, err, "API max retries must not be negative")
+
+	_, err = load([]string{"-api-call-budget", "-5"}, envFrom(nil))
+	assert.ErrorContains(t, err, "API call budget must not be negative")
+
+	_, err = load(nil, envFrom(map[string]string{EnvPrefix + "RESULT_MODE": "flat"}))
+	assert.ErrorContains(t, err, `invalid result mode "flat"`)
+
+	_, err = load([]string{"-argocd-namespace", ""}, envFrom(nil))
+	assert.ErrorContains(t, err, "Argo CD namespace must not be empty")
+
+	path = writeConfigFile(t, "suppressions:\n- namespace: argocd\n")
+	_, err = load([]string{"-config", path}, envFrom(nil))
+	assert.ErrorContains(t, err, `suppression 0: at least one rule ID or "*" is required`)
+
+	path = writeConfigFile(t, "suppressions:\n- rules: [ASA1]\n")
+	_, err = load([]string{"-config", path}, envFrom(nil))
+	assert.ErrorContains(t, err, `invalid rule ID "ASA1"`)
+
+	path = writeConfigFile(t, "suppressions:\n- rules: [ASA001]\n  name: \"[a\"\n")
+	_, err = load([]string{"-config", path}, envFrom(nil))
+	assert.ErrorContains(t, err, `invalid glob "[a"`)
+
+	path = writeConfigFile(t, "suppressions:\n- rules: [ASA001]\n  expires: next week\n")
+	_, err = load([]string{"-config", path}, envFrom(nil))
+	assert.ErrorContains(t, err, `invalid expiry "next week"`)
+
+	_, err = load(nil, envFrom(map[string]string{EnvPrefix + "WATCH": "sometimes"}))
+	assert.ErrorContains(t, err, "WATCH")
+
+	_, err = load([]string{"-resync-period", "-1m"}, envFrom(nil))
+	assert.ErrorContains(t, err, "resync period must not be negative")
+
+	_, err = load([]string{"-log-level", "verbose"}, envFrom(nil))
+	assert.ErrorContains(t, err, `invalid log level "verbose"`)
+
+	_, err = load(nil, envFrom(map[string]string{EnvPrefix + "LOG_FORMAT": "xml"}))
+	assert.ErrorContains(t, err, `invalid log format "xml"`)
+
+	_, err = load([]string{"-listen-address", ""}, envFrom(nil))
+	assert.ErrorContains(t, err, "listen address must not be empty")
+}
@@ -0,0 +1,50 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", FormatJSON)
+	require.NoError(t, err)
+
+	logger.Info("dropped")
+	logger.Warn("kept", "applicationSet", "guestbook")
+
+	var record map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	assert.Equal(t, "kept", record["msg"])
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "guestbook", record["applicationSet"])
+
+	_, err = New(&buf, "loud", FormatText)
+	assert.Error(t, err)
+	_, err = New(&buf, "info", "xml")
+	assert.Error(t, err)
+}
+
+func TestRequestID(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-correlation-id", "abc-123"))
+	assert.Equal(t, "abc-123", RequestID(ctx))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-1", "x-correlation-id", "abc-123"))
+	assert.Equal(t, "req-1", RequestID(ctx))
+
+	generated := RequestID(context.Background())
+	assert.Len(t, generated, 16)
+	assert.NotEqual(t, generated, RequestID(context.Background()))
+}
+
+func TestFromContext(t *testing.T) {
+	logger := Discard()
+	assert.Same(t, logger, FromContext(NewContext(context.Background(), logger)))
+	assert.NotNil(t, FromContext(context.Background()))
+}
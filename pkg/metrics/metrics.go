@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const namespace = "appset_analyzer"
+
+// Run results used as the "result" label of RunsTotal
+const (
+	RunResultSuccess = "success"
+	RunResultError   = "error"
+	// RunResultIncomplete is a run that completed but skipped checks it could not verify
+	RunResultIncomplete = "incomplete"
+)
+
+var (
+	// Registry holds all analyzer metrics plus the Go runtime and process collectors
+	Registry = prometheus.NewRegistry()
+
+	// RunsTotal counts Run calls by result
+	RunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "runs_total",
+		Help:      "Number of analyzer Run calls by result.",
+	}, []string{"result"})
+
+	// ClusterRunsTotal counts the analyses of each cluster by result, when several kubeconfig contexts are analyzed
+	ClusterRunsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cluster_runs_total",
+		Help:      "Number of analyses of a cluster by kubeconfig context and result, when several contexts are analyzed.",
+	}, []string{"cluster", "result"})
+
+	// RunDuration observes how long Run calls take
+	RunDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of analyzer Run calls.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	})
+
+	// APIRequestsTotal counts Kubernetes API calls by resource and verb
+	APIRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_api_requests_total",
+		Help:      "Number of Kubernetes API calls by group, version, resource and verb.",
+	}, []string{"group", "version", "resource", "verb"})
+
+	// APIErrorsTotal counts failed Kubernetes API calls by resource and verb
+	APIErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_api_errors_total",
+		Help:      "Number of failed Kubernetes API calls by group, version, resource and verb.",
+	}, []string{"group", "version", "resource", "verb"})
+
+	// APIRetriesTotal counts retries of Kubernetes API calls after transient errors
+	APIRetriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "kubernetes_api_retries_total",
+		Help:      "Number of Kubernetes API calls retried after a transient error, by group, version, resource and verb.",
+	}, []string{"group", "version", "resource", "verb"})
+
+	// ApplicationSetsScanned is the number of ApplicationSets analyzed in the last run
+	ApplicationSetsScanned = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "applicationsets_scanned",
+		Help:      "Number of ApplicationSets analyzed in the last run.",
+	})
+
+	// ApplicationsScanned is the number of Applications analyzed in the last run
+	ApplicationsScanned = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "applications_scanned",
+		Help:      "Number of generated Applications analyzed in the last run.",
+	})
+
+	// Findings is the number of findings in the last run by cluster, check and namespace
+	Findings = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "findings",
+		Help:      "Number of findings reported in the last run by kubeconfig context, check and namespace.",
+	}, []string{"cluster", "check", "namespace"})
+
+	// CacheSynced is 1 once the informer cache of a cluster used in watch mode has synced
+	CacheSynced = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "cache_synced",
+		Help:      "Whether the informer cache used in watch mode has synced (1) or not (0), by kubeconfig context.",
+	}, []string{"cluster"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RunsTotal,
+		ClusterRunsTotal,
+		RunDuration,
+		APIRequestsTotal,
+		APIErrorsTotal,
+		APIRetriesTotal,
+		ApplicationSetsScanned,
+		ApplicationsScanned,
+		Findings,
+		CacheSynced,
+	)
+}
+
+// ObserveAPICall records a Kubernetes API call and whether it failed
+func ObserveAPICall(gvr schema.GroupVersionResource, verb string, err error) {
+	APIRequestsTotal.WithLabelValues(gvr.Group, gvr.Version, gvr.Resource, verb).Inc()
+	if err != nil {
+		APIErrorsTotal.WithLabelValues(gvr.Group, gvr.Version, gvr.Resource, verb).Inc()
+	}
+}
+
+// ObserveAPIRetry records the retry of a Kubernetes API call
+func ObserveAPIRetry(gvr schema.GroupVersionResource, verb string) {
+	APIRetriesTotal.WithLabelValues(gvr.Group, gvr.Version, gvr.Resource, verb).Inc()
+}
+
+// ObserveRun records a finished Run call
+func ObserveRun(start time.Time, result string) {
+	RunsTotal.WithLabelValues(result).Inc()
+	RunDuration.Observe(time.Since(start).Seconds())
+}
+
+// ObserveClusterRun records the analysis of one of several clusters
+func ObserveClusterRun(cluster, result string) {
+	ClusterRunsTotal.WithLabelValues(cluster, result).Inc()
+}
+
+// FindingsKey identifies a findings gauge series
+type FindingsKey struct {
+	Cluster   string
+	Check     string
+	Namespace string
+}
+
+// SetRunStats replaces the per-run gauges with the results of the last run. Only the
+// findings series of clusters are replaced, so that the series of a cluster that could
+// not be analyzed keep the results of its last successful analysis.
+func SetRunStats(clusters []string, applicationSets, applications int, findings map[FindingsKey]int) {
+	ApplicationSetsScanned.Set(float64(applicationSets))
+	ApplicationsScanned.Set(float64(applications))
+	for _, cluster := range clusters {
+		Findings.DeletePartialMatch(prometheus.Labels{"cluster": cluster})
+	}
+	for key, count := range findings {
+		Findings.WithLabelValues(key.Cluster, key.Check, key.Namespace).Set(float64(count))
+	}
+}
+
+// Serve exposes the metrics on address under /metrics until ctx is cancelled
+func Serve(ctx context.Context, address string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{
+		Addr:              address,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("metrics server: %v", err)
+	}
+	return nil
+}
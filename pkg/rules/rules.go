@@ -0,0 +1,273 @@
+package rules
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/ranakan19/custom-analyzer/pkg/config"
+)
+
+// Severity ranks how urgently a finding needs attention
+type Severity string
+
+// Supported severities, from most to least severe
+const (
+	SeverityCritical Severity = "critical"
+	SeverityWarning  Severity = "warning"
+	SeverityInfo     Severity = "info"
+)
+
+// Rule describes one kind of finding reported by the analyzer. IDs are stable
+// across releases, so they can be used to filter or suppress findings.
+type Rule struct {
+	// ID is the stable identifier of the rule, e.g. ASA001
+	ID string `json:"id"`
+	// Severity is the default severity of the rule's findings
+	Severity Severity `json:"severity"`
+	// Check is the configurable check that reports the rule, or empty for analyzer-level rules
+	Check string `json:"check,omitempty"`
+	// Title is a short description of the rule
+	Title string `json:"title"`
+	// Doc links the documentation section that explains the rule's subject
+	Doc string `json:"doc,omitempty"`
+	// Field is the path of the field the rule inspects, on the ApplicationSet unless the
+	// rule is about generated Applications
+	Field string `json:"field,omitempty"`
+	// Explain describes Field in the style of kubectl explain, for field-level rules
+	Explain string `json:"explain,omitempty"`
+}
+
+// Documentation the rules link to
+const (
+	argoDocs              = "https://argo-cd.readthedocs.io/en/stable/"
+	appSetDocs            = argoDocs + "operator-manual/applicationset/"
+	applicationSetSpecDoc = appSetDocs + "applicationset-specification/"
+	generatorsDoc         = appSetDocs + "Generators/"
+	progressiveSyncsDoc   = appSetDocs + "Progressive-Syncs/"
+)
+
+// Rules reported by the analyzer. New rules get the next free ID; IDs are never reused.
+var (
+	ErrorOccurred = register(Rule{ID: "ASA001", Severity: SeverityCritical, Check: config.CheckConditions,
+		Title: "ApplicationSet has an ErrorOccurred condition",
+		Doc:   applicationSetSpecDoc, Field: "status.conditions"})
+	ParametersNotGenerated = register(Rule{ID: "ASA002", Severity: SeverityCritical, Check: config.CheckConditions,
+		Title: "ApplicationSet failed to generate parameters (ParametersGenerated=False)",
+		Doc:   generatorsDoc, Field: "status.conditions"})
+	ResourcesNotUpToDate = register(Rule{ID: "ASA003", Severity: SeverityWarning, Check: config.CheckConditions,
+		Title: "ApplicationSet resources are not up to date (ResourcesUpToDate=False)",
+		Doc:   applicationSetSpecDoc, Field: "status.conditions"})
+	Progressing = register(Rule{ID: "ASA004", Severity: SeverityWarning, Check: config.CheckProgressing,
+		Title: "ApplicationSet is progressing longer than the configured threshold",
+		Doc:   progressiveSyncsDoc, Field: "status.conditions"})
+	InvalidGenerators = register(Rule{ID: "ASA005", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "ApplicationSet generators cannot be parsed",
+		Doc:   generatorsDoc, Field: "spec.generators"})
+	NoGenerators = register(Rule{ID: "ASA006", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "ApplicationSet has no generators",
+		Doc:   generatorsDoc, Field: "spec.generators",
+		Explain: "generators <[]Object> -required-\n  Generators produce the parameters rendered into the template, one Application per parameter set."})
+	InvalidGenerator = register(Rule{ID: "ASA007", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Generator is not an object",
+		Doc:   generatorsDoc, Field: "spec.generators[]"})
+	EmptyGenerator = register(Rule{ID: "ASA008", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Generator is empty",
+		Doc:   generatorsDoc, Field: "spec.generators[]",
+		Explain: "generators[] <Object>\n  Exactly one generator type, such as list, clusters, git, matrix or merge, must be set."})
+	GitGeneratorNoRepoURL = register(Rule{ID: "ASA009", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Git generator has no repoURL",
+		Doc:   appSetDocs + "Generators-Git/", Field: "spec.generators[].git.repoURL",
+		Explain: "repoURL <string> -required-\n  URL of the Git repository whose directories or files generate the parameters."})
+	ListGeneratorNoElements = register(Rule{ID: "ASA010", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "List generator has neither elements nor elementsYaml",
+		Doc:   appSetDocs + "Generators-List/", Field: "spec.generators[].list.elements",
+		Explain: "elements <[]Object>\n  Literal parameter sets, one per Application. Either elements or elementsYaml must be set."})
+	ListGeneratorEmptyElements = register(Rule{ID: "ASA011", Severity: SeverityWarning, Check: config.CheckGenerators,
+		Title: "List generator has an empty elements array",
+		Doc:   appSetDocs + "Generators-List/", Field: "spec.generators[].list.elements"})
+	ClusterGeneratorUnfiltered = register(Rule{ID: "ASA012", Severity: SeverityInfo, Check: config.CheckGenerators,
+		Title: "Cluster generator has neither selector nor values and targets every cluster",
+		Doc:   appSetDocs + "Generators-Cluster/", Field: "spec.generators[].clusters.selector",
+		Explain: "selector <Object>\n  Label selector for the cluster secrets to generate parameters for; empty selects every cluster."})
+	ClusterGeneratorEmptyValues = register(Rule{ID: "ASA013", Severity: SeverityInfo, Check: config.CheckGenerators,
+		Title: "Cluster generator has empty values",
+		Doc:   appSetDocs + "Generators-Cluster/", Field: "spec.generators[].clusters.values",
+		Explain: "values <map[string]string>\n  Additional key/value pairs passed to the template as values.<key>."})
+	GeneratedApplicationUnhealthy = register(Rule{ID: "ASA014", Severity: SeverityCritical, Check: config.CheckApplications,
+		Title: "ApplicationSet status reports a generated Application as not healthy",
+		Doc:   progressiveSyncsDoc, Field: "status.applicationStatus"})
+	GeneratedApplicationOutOfSync = register(Rule{ID: "ASA015", Severity: SeverityWarning, Check: config.CheckApplications,
+		Title: "ApplicationSet status reports a generated Application as not synced",
+		Doc:   progressiveSyncsDoc, Field: "status.applicationStatus"})
+	NoGeneratedApplications = register(Rule{ID: "ASA016", Severity: SeverityWarning, Check: config.CheckApplications,
+		Title: "ApplicationSet has no generated Applications",
+		Doc:   generatorsDoc, Field: "spec.generators"})
+	ApplicationUnhealthy = register(Rule{ID: "ASA017", Severity: SeverityCritical, Check: config.CheckApplications,
+		Title: "Generated Application is not healthy",
+		Doc:   argoDocs + "operator-manual/health/", Field: "status.health"})
+	ApplicationOutOfSync = register(Rule{ID: "ASA018", Severity: SeverityWarning, Check: config.CheckApplications,
+		Title: "Generated Application is not synced",
+		Doc:   argoDocs + "user-guide/diffing/", Field: "status.sync"})
+	ApplicationOperationFailed = register(Rule{ID: "ASA019", Severity: SeverityCritical, Check: config.CheckApplications,
+		Title: "Generated Application has a failed operation",
+		Doc:   argoDocs + "user-guide/sync-options/", Field: "status.operationState"})
+	AnalysisTimedOut = register(Rule{ID: "ASA020", Severity: SeverityWarning,
+		Title: "Analysis of an ApplicationSet timed out; results may be incomplete"})
+	MissingPermission = register(Rule{ID: "ASA021", Severity: SeverityCritical,
+		Title: "Analyzer lacks a permission; dependent checks were skipped",
+		Doc:   "https://kubernetes.io/docs/reference/access-authn-authz/rbac/"})
+	ClusterUnreachable = register(Rule{ID: "ASA022", Severity: SeverityCritical,
+		Title: "Kubernetes cluster cannot be reached",
+		Doc:   "https://kubernetes.io/docs/concepts/configuration/organize-cluster-access-kubeconfig/"})
+	ListFailed = register(Rule{ID: "ASA023", Severity: SeverityCritical,
+		Title: "ApplicationSets cannot be listed",
+		Doc:   "https://kubernetes.io/docs/reference/using-api/api-concepts/"})
+	StaleApplicationStatus = register(Rule{ID: "ASA024", Severity: SeverityInfo, Check: config.CheckApplications,
+		Title: "ApplicationSet status disagrees with the live Application and is stale",
+		Doc:   progressiveSyncsDoc, Field: "status.applicationStatus"})
+	MatrixGeneratorChildCount = register(Rule{ID: "ASA025", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Matrix generator does not have exactly two child generators",
+		Doc:   appSetDocs + "Generators-Matrix/", Field: "spec.generators[].matrix.generators",
+		Explain: "generators <[]Object> -required-\n  The two generators whose parameter sets are combined as a Cartesian product."})
+	GeneratorNestingTooDeep = register(Rule{ID: "ASA026", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Matrix or merge generator is nested deeper than Argo CD supports",
+		Doc:   appSetDocs + "Generators-Matrix/", Field: "spec.generators[].matrix.generators"})
+	MatrixGeneratorParameterCollision = register(Rule{ID: "ASA027", Severity: SeverityWarning, Check: config.CheckGenerators,
+		Title: "Matrix generator children produce parameters with the same name, which override each other",
+		Doc:   appSetDocs + "Generators-Matrix/", Field: "spec.generators[].matrix.generators"})
+	MergeGeneratorNoMergeKeys = register(Rule{ID: "ASA028", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Merge generator has no mergeKeys",
+		Doc:   appSetDocs + "Generators-Merge/", Field: "spec.generators[].merge.mergeKeys",
+		Explain: "mergeKeys <[]string> -required-\n  Parameters whose values identify the base parameter sets that secondary generators override."})
+	MergeGeneratorNoBase = register(Rule{ID: "ASA029", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Merge generator has no base generator",
+		Doc:   appSetDocs + "Generators-Merge/", Field: "spec.generators[].merge.generators",
+		Explain: "generators <[]Object> -required-\n  The first generator is the base; the following generators override its parameter sets."})
+	MergeGeneratorMissingMergeKey = register(Rule{ID: "ASA030", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Merge generator child does not produce every merge key",
+		Doc:   appSetDocs + "Generators-Merge/", Field: "spec.generators[].merge.mergeKeys"})
+	MergeGeneratorNoOverrides = register(Rule{ID: "ASA031", Severity: SeverityWarning, Check: config.CheckGenerators,
+		Title: "Merge generator child never matches the base generator and overrides nothing",
+		Doc:   appSetDocs + "Generators-Merge/", Field: "spec.generators[].merge.generators"})
+	SCMProviderNotConfigured = register(Rule{ID: "ASA032", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "SCM Provider generator does not configure exactly one provider",
+		Doc:   appSetDocs + "Generators-SCM-Provider/", Field: "spec.generators[].scmProvider"})
+	SCMProviderMissingField = register(Rule{ID: "ASA033", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "SCM Provider generator is missing a field required by its provider",
+		Doc:   appSetDocs + "Generators-SCM-Provider/", Field: "spec.generators[].scmProvider.<provider>",
+		Explain: "<provider> <Object>\n  github needs organization, gitlab group, bitbucketServer project and api, bitbucket owner and user,\n  gitea owner and api, azureDevOps organization and teamProject."})
+	GeneratorSecretMissing = register(Rule{ID: "ASA034", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Generator references a secret that does not exist",
+		Doc:   generatorsDoc, Field: "spec.generators[].<generator>.<provider>.tokenRef",
+		Explain: "tokenRef <Object>\n  secretName and key of the secret holding the API token, in the ApplicationSet's namespace."})
+	GeneratorSecretKeyMissing = register(Rule{ID: "ASA035", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Generator references a key that its secret does not have",
+		Doc:   generatorsDoc, Field: "spec.generators[].<generator>.<provider>.tokenRef.key"})
+	InvalidGeneratorFilter = register(Rule{ID: "ASA036", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Generator filter has an invalid regular expression or path",
+		Doc:   generatorsDoc, Field: "spec.generators[].<generator>.filters"})
+	SCMProviderCloneProtocolMismatch = register(Rule{ID: "ASA037", Severity: SeverityWarning, Check: config.CheckGenerators,
+		Title: "SCM Provider generator cloneProtocol does not fit its provider or API",
+		Doc:   appSetDocs + "Generators-SCM-Provider/", Field: "spec.generators[].scmProvider.cloneProtocol",
+		Explain: "cloneProtocol <string>\n  Protocol of the generated repository URLs: ssh or https, and https-fips for awsCodeCommit."})
+	PullRequestNotConfigured = register(Rule{ID: "ASA038", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Pull Request generator does not configure exactly one provider",
+		Doc:   appSetDocs + "Generators-Pull-Request/", Field: "spec.generators[].pullRequest"})
+	PullRequestMissingField = register(Rule{ID: "ASA039", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Pull Request generator is missing a field required by its provider",
+		Doc:   appSetDocs + "Generators-Pull-Request/", Field: "spec.generators[].pullRequest.<provider>",
+		Explain: "<provider> <Object>\n  github needs owner and repo, gitlab project, gitea owner, repo and api, bitbucketServer project, repo and api,\n  bitbucket owner and repo, azuredevops organization, project and repo."})
+	StalePreviewApplication = register(Rule{ID: "ASA040", Severity: SeverityWarning, Check: config.CheckApplications,
+		Title: "Preview Application outlived its pull request",
+		Doc:   appSetDocs + "Generators-Pull-Request/", Field: "spec.generators[].pullRequest.requeueAfterSeconds",
+		Explain: "requeueAfterSeconds <integer>\n  How often pull requests are polled, 1800 by default; Applications of closed pull requests are deleted on the next poll."})
+	GeneratorConfigMapMissing = register(Rule{ID: "ASA041", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Generator references a ConfigMap that does not exist",
+		Doc:   generatorsDoc, Field: "spec.generators[].<generator>.configMapRef",
+		Explain: "configMapRef <string|Object>\n  Name of the ConfigMap in the Argo CD namespace that configures a ClusterDecisionResource or Plugin generator."})
+	GeneratorConfigMapKeyMissing = register(Rule{ID: "ASA042", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Generator ConfigMap is missing a required key",
+		Doc:   generatorsDoc, Field: "spec.generators[].<generator>.configMapRef",
+		Explain: "configMapRef <string|Object>\n  ClusterDecisionResource ConfigMaps need apiVersion, kind, statusListKey and matchKey; Plugin ConfigMaps need token and baseUrl."})
+	DuckTypeResourceMissing = register(Rule{ID: "ASA043", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "ClusterDecisionResource generator does not select an existing duck-typed resource",
+		Doc:   appSetDocs + "Generators-Cluster-Decision-Resource/", Field: "spec.generators[].clusterDecisionResource.name",
+		Explain: "name <string>\n  Name of the duck-typed resource in the ApplicationSet's namespace; alternatively labelSelector selects it by label."})
+	DuckTypeStatusListMissing = register(Rule{ID: "ASA044", Severity: SeverityWarning, Check: config.CheckGenerators,
+		Title: "Duck-typed resource has no usable status list of cluster decisions",
+		Doc:   appSetDocs + "Generators-Cluster-Decision-Resource/", Field: "spec.generators[].clusterDecisionResource.configMapRef",
+		Explain: "configMapRef <string>\n  Its statusListKey names the list under status, and matchKey the key of each entry that holds the cluster name."})
+	PluginUnreachable = register(Rule{ID: "ASA045", Severity: SeverityCritical, Check: config.CheckGenerators,
+		Title: "Plugin generator baseUrl is invalid or points to a Service that does not exist",
+		Doc:   appSetDocs + "Generators-Plugin/", Field: "spec.generators[].plugin.configMapRef.name",
+		Explain: "name <string>\n  Name of the ConfigMap whose baseUrl is the plugin service URL and whose token authenticates against it."})
+	RunDeadlineExceeded = register(Rule{ID: "ASA046", Severity: SeverityWarning,
+		Title: "Run request deadline expired before the analysis completed; results may be incomplete"})
+	NoApplicationSets = register(Rule{ID: "ASA047", Severity: SeverityInfo,
+		Title: "No ApplicationSets were found in the analyzed scope"})
+	GeneratorReferenceUnverified = register(Rule{ID: "ASA048", Severity: SeverityWarning, Check: config.CheckGenerators,
+		Title: "A secret, ConfigMap or Service referenced by a generator could not be read; its check was skipped",
+		Doc:   generatorsDoc, Field: "spec.generators",
+		Explain: "generators <[]Object> -required-\n  Generators may reference secrets, ConfigMaps and Services that the analyzer reads to verify them."})
+)
+
+var catalog = make(map[string]Rule)
+
+func register(rule Rule) Rule {
+	if _, exists := catalog[rule.ID]; exists {
+		panic(fmt.Sprintf("duplicate rule ID %s", rule.ID))
+	}
+	catalog[rule.ID] = rule
+	return rule
+}
+
+// Catalog returns every rule, ordered by ID
+func Catalog() []Rule {
+	rules := make([]Rule, 0, len(catalog))
+	for _, rule := range catalog {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].ID < rules[j].ID
+	})
+	return rules
+}
+
+// WriteCatalog writes the catalog as a table of rule IDs, severities, checks and titles
+func WriteCatalog(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tSEVERITY\tCHECK\tDESCRIPTION")
+	for _, rule := range Catalog() {
+		check := rule.Check
+		if check == "" {
+			check = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", rule.ID, rule.Severity, check, rule.Title)
+	}
+	return tw.Flush()
+}
+
+// Lookup returns the rule with the given ID
+func Lookup(id string) (Rule, bool) {
+	rule, ok := catalog[id]
+	return rule, ok
+}
+
+// Format renders a finding of the rule as "[ID/severity] message"
+func (r Rule) Format(format string, args ...interface{}) string {
+	return fmt.Sprintf("[%s/%s] %s", r.ID, r.Severity, fmt.Sprintf(format, args...))
+}
+
+var findingPattern = regexp.MustCompile(`(?s)^\[(ASA\d{3})/(critical|warning|info)\] (.*)$`)
+
+// Parse splits a finding rendered by Format into its rule ID, severity and message
+func Parse(text string) (id string, severity Severity, message string, ok bool) {
+	match := findingPattern.FindStringSubmatch(text)
+	if match == nil {
+		return "", "", text, false
+	}
+	return match[1], Severity(match[2]), match[3], true
+}
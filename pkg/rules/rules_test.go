@@ -0,0 +1,66 @@
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalog(t *testing.T) {
+	catalog := Catalog()
+	for i, rule := range catalog {
+		assert.Equal(t, fmt.Sprintf("ASA%03d", i+1), rule.ID, "rule IDs should be sequential")
+		assert.Contains(t, []Severity{SeverityCritical, SeverityWarning, SeverityInfo}, rule.Severity)
+		assert.NotEmpty(t, rule.Title)
+		if rule.Check != "" {
+			assert.NotEmpty(t, rule.Doc, "rule %s should link its documentation", rule.ID)
+			assert.NotEmpty(t, rule.Field, "rule %s should name the field it inspects", rule.ID)
+		}
+		if rule.Explain != "" {
+			assert.NotEmpty(t, rule.Field, "rule %s explains a field it does not name", rule.ID)
+		}
+
+		found, ok := Lookup(rule.ID)
+		assert.True(t, ok)
+		assert.Equal(t, rule, found)
+	}
+
+	_, ok := Lookup("ASA999")
+	assert.False(t, ok)
+}
+
+func TestWriteCatalog(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, WriteCatalog(&buf))
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	catalog := Catalog()
+	if assert.Len(t, lines, len(catalog)+1) {
+		assert.Equal(t, []string{"RULE", "SEVERITY", "CHECK", "DESCRIPTION"}, strings.Fields(lines[0]))
+		for i, rule := range catalog {
+			assert.True(t, strings.HasPrefix(lines[i+1], rule.ID+" "), "line %d should list %s", i+1, rule.ID)
+			assert.Contains(t, lines[i+1], " "+string(rule.Severity)+" ")
+			assert.True(t, strings.HasSuffix(lines[i+1], rule.Title))
+		}
+	}
+	assert.Equal(t, []string{"ASA001", "critical", "conditions"}, strings.Fields(lines[1])[:3])
+	assert.Equal(t, []string{"ASA020", "warning", "-"}, strings.Fields(lines[20])[:3])
+}
+
+func TestFormatAndParse(t *testing.T) {
+	text := ErrorOccurred.Format("ApplicationSet %s/%s has error condition: %s", "argocd", "guestbook", "line one\nline two")
+	assert.Equal(t, "[ASA001/critical] ApplicationSet argocd/guestbook has error condition: line one\nline two", text)
+
+	id, severity, message, ok := Parse(text)
+	assert.True(t, ok)
+	assert.Equal(t, "ASA001", id)
+	assert.Equal(t, SeverityCritical, severity)
+	assert.Equal(t, "ApplicationSet argocd/guestbook has error condition: line one\nline two", message)
+
+	_, _, message, ok = Parse("free-form finding")
+	assert.False(t, ok)
+	assert.Equal(t, "free-form finding", message)
+}
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Pinger checks whether the analyzer's backend (the Kubernetes API server) is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// WatchHealth periodically pings the backend and sets the serving status of
+// service on the health server accordingly, until ctx is cancelled.
+// The overall server status ("") is left untouched so that it can be used as
+// a liveness signal while the service status is used for readiness.
+func WatchHealth(ctx context.Context, healthServer *health.Server, service string, pinger Pinger, interval time.Duration) {
+	check := func() {
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		defer cancel()
+		if err := pinger.Ping(pingCtx); err != nil {
+			slog.Warn("Health check failed", "error", err)
+			healthServer.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+			return
+		}
+		healthServer.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
+
+// Serve serves grpcServer on lis until ctx is cancelled, then stops accepting
+// new RPCs and waits up to drainTimeout for in-flight RPCs before forcing a stop.
+// The health server, if any, is switched to NOT_SERVING as soon as shutdown begins.
+func Serve(ctx context.Context, grpcServer *grpc.Server, lis net.Listener, healthServer *health.Server, drainTimeout time.Duration) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(lis)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("Shutting down, draining in-flight requests", "drainTimeout", drainTimeout)
+	if healthServer != nil {
+		healthServer.Shutdown()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(drainTimeout):
+		slog.Warn("Drain timeout exceeded, forcing shutdown")
+		grpcServer.Stop()
+		<-stopped
+	}
+
+	return <-serveErr
+}
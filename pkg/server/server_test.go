@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	rpc "buf.build/gen/go/k8sgpt-ai/k8sgpt/grpc/go/schema/v1/schemav1grpc"
+	v1 "buf.build/gen/go/k8sgpt-ai/k8sgpt/protocolbuffers/go/schema/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakePinger struct {
+	mu  sync.Mutex
+	err error
+}
+
+func (p *fakePinger) Ping(context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *fakePinger) setErr(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func servingStatus(t *testing.T, healthServer *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+func TestWatchHealth(t *testing.T) {
+	const service = "schema.v1.CustomAnalyzerService"
+	healthServer := health.NewServer()
+	pinger := &fakePinger{err: errors.New("connection refused")}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go WatchHealth(ctx, healthServer, service, pinger, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return servingStatus(t, healthServer, service) == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 5*time.Millisecond)
+
+	pinger.setErr(nil)
+	assert.Eventually(t, func() bool {
+		return servingStatus(t, healthServer, service) == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 5*time.Millisecond)
+
+	// Liveness status is not affected by API reachability
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus(t, healthServer, ""))
+}
+
+// blockingAnalyzer blocks Run until release is closed
+type blockingAnalyzer struct {
+	rpc.UnimplementedCustomAnalyzerServiceServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingAnalyzer) Run(ctx context.Context, _ *v1.RunRequest) (*v1.RunResponse, error) {
+	close(b.started)
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return &v1.RunResponse{Result: &v1.Result{Name: "blocking"}}, nil
+}
+
+func startServer(t *testing.T, drainTimeout time.Duration) (*blockingAnalyzer, *health.Server, string, context.CancelFunc, chan error) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	analyzer := &blockingAnalyzer{started: make(chan struct{}), release: make(chan struct{})}
+	grpcServer := grpc.NewServer()
+	rpc.RegisterCustomAnalyzerServiceServer(grpcServer, analyzer)
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, grpcServer, lis, healthServer, drainTimeout)
+	}()
+	return analyzer, healthServer, lis.Addr().String(), cancel, done
+}
+
+func runAsync(t *testing.T, address string) chan error {
+	t.Helper()
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := rpc.NewCustomAnalyzerServiceClient(conn).Run(context.Background(), &v1.RunRequest{})
+		result <- err
+	}()
+	return result
+}
+
+func TestServe_GracefulShutdownDrainsInFlightRPCs(t *testing.T) {
+	analyzer, healthServer, address, cancel, done := startServer(t, 5*time.Second)
+
+	result := runAsync(t, address)
+	<-analyzer.started
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return servingStatus(t, healthServer, "") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 5*time.Millisecond, "health must report NOT_SERVING once shutdown starts")
+
+	close(analyzer.release)
+	assert.NoError(t, <-result, "in-flight RPC should complete during drain")
+	assert.NoError(t, <-done)
+}
+
+func TestServe_DrainTimeoutForcesStop(t *testing.T) {
+	analyzer, _, address, cancel, done := startServer(t, 50*time.Millisecond)
+	defer close(analyzer.release)
+
+	result := runAsync(t, address)
+	<-analyzer.started
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after drain timeout")
+	}
+	assert.Error(t, <-result, "in-flight RPC should be aborted by forced stop")
+}